@@ -33,7 +33,7 @@ var _ = Describe("backtraceFromErrorWithStackTrace", func() {
 			v, ok := test.err.(stackTracer)
 
 			Expect(ok).To(BeTrue())
-			packageName, _ := backtraceFromErrorWithStackTrace(v)
+			packageName, _, _ := backtraceFromErrorWithStackTrace(v)
 			Expect(packageName).To(Equal(test.packageName))
 		}
 	})
@@ -0,0 +1,74 @@
+package gobrake
+
+// maxBatchErrors is the max number of distinct errors listed in a batch
+// notice.
+const maxBatchErrors = 100
+
+type batchError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// NotifyBatch reports failures of a batch operation as a single notice
+// instead of a notice per item. The first error is used as the notice
+// error and params/batch lists distinct errors with their counts, capped
+// at 100 distinct errors; the number of errors not listed is reported as
+// omitted. Shared params are added to the notice params.
+func (n *Notifier) NotifyBatch(errs []error, sharedParams map[string]interface{}) {
+	if len(errs) == 0 || !n.errorsEnabled() {
+		return
+	}
+
+	var distinct []*batchError
+	var total, omitted int
+	index := make(map[string]*batchError)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		total++
+
+		typ := getTypeName(err)
+		key := typ + ": " + err.Error()
+		if e, ok := index[key]; ok {
+			e.Count++
+			continue
+		}
+		if len(distinct) >= maxBatchErrors {
+			omitted++
+			continue
+		}
+
+		e := &batchError{
+			Type:    typ,
+			Message: err.Error(),
+			Count:   1,
+		}
+		index[key] = e
+		distinct = append(distinct, e)
+	}
+	if len(distinct) == 0 {
+		return
+	}
+
+	notice := n.Notice(firstError(errs), nil, 1)
+	for k, v := range sharedParams {
+		notice.Params[k] = v
+	}
+	notice.Params["batch"] = map[string]interface{}{
+		"total":   total,
+		"omitted": omitted,
+		"errors":  distinct,
+	}
+	n.SendNoticeAsync(notice)
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package gobrake_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NotifyBatch", func() {
+	var notifier *gobrake.Notifier
+	var notices []*gobrake.Notice
+
+	BeforeEach(func() {
+		notices = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			var notice *gobrake.Notice
+			err = json.Unmarshal(b, &notice)
+			if err != nil {
+				panic(err)
+			}
+			notices = append(notices, notice)
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("groups batch errors into one notice", func() {
+		var errs []error
+		for i := 0; i < 150; i++ {
+			errs = append(errs, errors.New("row is invalid"), nil)
+		}
+		for i := 0; i < 105; i++ {
+			errs = append(errs, fmt.Errorf("row %d: duplicate key", i))
+		}
+
+		notifier.NotifyBatch(errs, map[string]interface{}{"job": "import"})
+		notifier.Flush()
+
+		Expect(notices).To(HaveLen(1))
+		notice := notices[0]
+		Expect(notice.Errors[0].Message).To(Equal("row is invalid"))
+		Expect(notice.Params["job"]).To(Equal("import"))
+
+		batch := notice.Params["batch"].(map[string]interface{})
+		Expect(batch["total"]).To(Equal(255.0))
+		Expect(batch["omitted"]).To(Equal(6.0))
+
+		listed := batch["errors"].([]interface{})
+		Expect(listed).To(HaveLen(100))
+		Expect(listed[0]).To(Equal(map[string]interface{}{
+			"type":    "*errors.errorString",
+			"message": "row is invalid",
+			"count":   150.0,
+		}))
+	})
+
+	It("does nothing without errors", func() {
+		notifier.NotifyBatch([]error{nil}, nil)
+		notifier.Flush()
+		Expect(notices).To(BeEmpty())
+	})
+})
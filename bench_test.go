@@ -42,17 +42,51 @@ func BenchmarkSendNotice(b *testing.B) {
 }
 
 func BenchmarkNotifyRequest(b *testing.B) {
-	notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
-		ProjectId:  1,
-		ProjectKey: "",
-	})
+	benchmarkNotifyRequest(b, &gobrake.NotifierOptions{}, 100)
+}
+
+// BenchmarkNotifyRequestHistogram uses fixed histogram buckets that are
+// cheap to update, so it mostly measures keying and locking overhead.
+func BenchmarkNotifyRequestHistogram(b *testing.B) {
+	benchmarkNotifyRequest(b, &gobrake.NotifierOptions{
+		StatsHistogramBuckets: []float64{10, 100, 1000},
+	}, 100)
+}
+
+func BenchmarkNotifyRequestSingleRoute(b *testing.B) {
+	benchmarkNotifyRequest(b, &gobrake.NotifierOptions{
+		StatsHistogramBuckets: []float64{10, 100, 1000},
+	}, 1)
+}
+
+// BenchmarkNotifyRequestSingleRouteParallel runs many goroutines per CPU
+// against a single route with t-digests, the hot path where all requests
+// contend on one stat and sharding does not help.
+func BenchmarkNotifyRequestSingleRouteParallel(b *testing.B) {
+	b.SetParallelism(8)
+	benchmarkNotifyRequest(b, &gobrake.NotifierOptions{}, 1)
+}
+
+// benchmarkNotifyRequest notifies about n routes in parallel. Stats are
+// flushed to a local server that accepts them.
+func benchmarkNotifyRequest(b *testing.B, opt *gobrake.NotifierOptions, n int) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	opt.ProjectId = 1
+	opt.ProjectKey = "key"
+	opt.Host = server.URL
+	opt.DisableRemoteConfig = true
+	notifier := gobrake.NewNotifierWithOptions(opt)
+	defer notifier.Close()
 
 	tm, err := time.Parse(time.RFC3339, "2018-01-01T00:00:00Z")
 	if err != nil {
 		b.Fatal(err)
 	}
 
-	const n = 100
 	reqs := make([]*gobrake.RequestInfo, n)
 	for i := 0; i < n; i++ {
 		reqs[i] = &gobrake.RequestInfo{
@@ -64,6 +98,7 @@ func BenchmarkNotifyRequest(b *testing.B) {
 		}
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		var i int
@@ -0,0 +1,289 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type routeMetricCtxKey struct{}
+
+// RouteMetric collects the duration of a request and breaks it down into
+// named groups, e.g. sql or http, which are reported with
+// Notifier.NotifyRouteMetric.
+type RouteMetric struct {
+	Method     string
+	Route      string
+	StatusCode int
+	Start      time.Time
+	End        time.Time
+
+	mu     sync.Mutex
+	groups map[string]time.Duration
+}
+
+// NewRouteMetric starts the route metric and returns the context that
+// carries it.
+func NewRouteMetric(ctx context.Context, method, route string) (context.Context, *RouteMetric) {
+	metric := &RouteMetric{
+		Method: method,
+		Route:  route,
+		Start:  time.Now(),
+	}
+	return context.WithValue(ctx, routeMetricCtxKey{}, metric), metric
+}
+
+// ContextRouteMetric returns the route metric carried by the context or nil.
+func ContextRouteMetric(ctx context.Context) *RouteMetric {
+	metric, _ := ctx.Value(routeMetricCtxKey{}).(*RouteMetric)
+	return metric
+}
+
+// AddGroup adds the duration to the named group. It is safe to call on
+// nil metric.
+func (m *RouteMetric) AddGroup(name string, dur time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	if m.groups == nil {
+		m.groups = make(map[string]time.Duration)
+	}
+	m.groups[name] += dur
+	m.mu.Unlock()
+}
+
+// StartSpan starts timing the named group. The duration is added to the
+// group when the span is finished.
+func (m *RouteMetric) StartSpan(name string) *Span {
+	return &Span{
+		metric: m,
+		name:   name,
+		start:  time.Now(),
+	}
+}
+
+// Groups returns a copy of the durations collected so far.
+func (m *RouteMetric) Groups() map[string]time.Duration {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make(map[string]time.Duration, len(m.groups))
+	for k, v := range m.groups {
+		groups[k] = v
+	}
+	return groups
+}
+
+// Span measures the duration of a single operation within a route.
+type Span struct {
+	metric *RouteMetric
+	name   string
+	start  time.Time
+}
+
+// Finish stops the span and adds its duration to the route metric.
+func (s *Span) Finish() {
+	s.metric.AddGroup(s.name, time.Since(s.start))
+}
+
+type routeBreakdown struct {
+	*routeStat
+	Groups map[string]*routeStat `json:"groups"`
+
+	opt *NotifierOptions
+}
+
+type routeKeyBreakdown struct {
+	routeKeyJSON
+	routeBreakdown
+}
+
+// routeBreakdowns aggregates durations of route metric groups and
+// periodically sends collected data to Airbrake.
+type routeBreakdowns struct {
+	opt          *NotifierOptions
+	remoteConfig *remoteConfig
+	breaker      *circuitBreaker
+	metrics      *notifierMetrics
+	unsupported  unsupportedStream
+
+	// mu is read-locked while a breakdown is updated and write-locked when
+	// the flush window is swapped, so breakdowns are never updated while
+	// they are being sent.
+	mu sync.RWMutex
+	m  map[routeKey]*routeBreakdown
+
+	flushTimer *time.Timer
+}
+
+func newRouteBreakdowns(opt *NotifierOptions, rc *remoteConfig) *routeBreakdowns {
+	return &routeBreakdowns{
+		opt:          opt,
+		remoteConfig: rc,
+		unsupported:  unsupportedStream{name: "routeBreakdowns"},
+	}
+}
+
+func (s *routeBreakdowns) init() {
+	if s.m == nil && s.flushTimer == nil {
+		s.m = make(map[routeKey]*routeBreakdown)
+		s.flushTimer = time.AfterFunc(flushPeriod, s.flush)
+	}
+}
+
+func (s *routeBreakdowns) flush() {
+	m := s.swap()
+	s.sendAll(context.Background(), m)
+}
+
+// swap returns collected breakdowns and resets the flush window.
+func (s *routeBreakdowns) swap() map[routeKey]*routeBreakdown {
+	s.mu.Lock()
+
+	m := s.m
+	s.m = nil
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+
+	s.mu.Unlock()
+
+	return m
+}
+
+func (s *routeBreakdowns) sendAll(ctx context.Context, m map[routeKey]*routeBreakdown) {
+	if len(m) == 0 || s.unsupported.Disabled() {
+		return
+	}
+
+	start := time.Now()
+	err := s.send(ctx, m)
+	s.metrics.flush(err, time.Since(start))
+	if err != nil && !s.unsupported.Check(s.opt, err) {
+		s.opt.logger().Printf("routeBreakdowns.send failed: %s", err)
+	}
+}
+
+type routesBreakdownsJSONRequest struct {
+	Routes []routeKeyBreakdown `json:"routes"`
+}
+
+func (s *routeBreakdowns) send(ctx context.Context, m map[routeKey]*routeBreakdown) error {
+	var routes []routeKeyBreakdown
+	var digests TDigestStats
+	for k, v := range m {
+		v.mu.Lock()
+		if s.opt.statsExpired(k.Time()) {
+			s.metrics.expired(v.Count)
+			v.mu.Unlock()
+			continue
+		}
+
+		err := v.compress()
+		if err == nil {
+			v.diagnose(&digests)
+			for _, group := range v.Groups {
+				err = group.compress()
+				if err != nil {
+					break
+				}
+				group.diagnose(&digests)
+			}
+		}
+		v.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		routes = append(routes, routeKeyBreakdown{
+			routeKeyJSON:   k.json(),
+			routeBreakdown: *v,
+		})
+	}
+
+	s.metrics.breakdownsTDigest(digests)
+
+	jsonReq := routesBreakdownsJSONRequest{
+		Routes: routes,
+	}
+	apiURL := fmt.Sprintf("%s/api/v5/projects/%d/routes-breakdowns",
+		s.remoteConfig.APMHost(s.opt), s.opt.ProjectId)
+	return sendStats(ctx, s.opt, s.breaker, apiURL, jsonReq)
+}
+
+func (s *routeBreakdowns) Notify(key routeKey, total time.Duration, groups map[string]time.Duration) error {
+	if s.unsupported.Disabled() {
+		return nil
+	}
+
+	b := s.rlockBreakdown(key)
+	defer s.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.Add(durationMs(total))
+	if err != nil {
+		return err
+	}
+
+	var other = total
+	for name, dur := range groups {
+		err := b.group(name).Add(durationMs(dur))
+		if err != nil {
+			return err
+		}
+		other -= dur
+	}
+	if other > 0 && len(groups) > 0 {
+		return b.group("other").Add(durationMs(other))
+	}
+	return nil
+}
+
+// rlockBreakdown read-locks s.mu and returns the breakdown of the key in
+// the current flush window, creating it if needed. The caller must call
+// s.mu.RUnlock.
+func (s *routeBreakdowns) rlockBreakdown(key routeKey) *routeBreakdown {
+	s.mu.RLock()
+	for {
+		if b, ok := s.m[key]; ok {
+			return b
+		}
+		s.mu.RUnlock()
+
+		s.mu.Lock()
+		s.init()
+		if _, ok := s.m[key]; !ok {
+			s.m[key.intern()] = &routeBreakdown{
+				opt:       s.opt,
+				routeStat: newRouteStat(s.opt),
+				Groups:    make(map[string]*routeStat),
+			}
+		}
+		s.mu.Unlock()
+
+		s.mu.RLock()
+	}
+}
+
+func (b *routeBreakdown) group(name string) *routeStat {
+	stat, ok := b.Groups[name]
+	if !ok {
+		stat = newRouteStat(b.opt)
+		b.Groups[name] = stat
+	}
+	return stat
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,149 @@
+package gobrake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultBreakerCooldown = time.Minute
+
+var errBreakerOpen = errors.New(
+	"gobrake: circuit breaker is open (data is dropped)")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops sending data to Airbrake after BreakerThreshold
+// consecutive delivery failures. After BreakerCooldown a single probe
+// request is let through while the rest is dropped until the probe either
+// closes the breaker or opens it for another cooldown. Methods are safe to call on nil breaker which means that it
+// is disabled.
+type circuitBreaker struct {
+	opt *NotifierOptions
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+	dropped   int
+}
+
+func newCircuitBreaker(opt *NotifierOptions) *circuitBreaker {
+	return &circuitBreaker{
+		opt: opt,
+	}
+}
+
+// Allow reports whether a request can be sent. It must be followed by
+// Done when it returns true, so the half-open probe is finished.
+func (b *circuitBreaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			b.dropped++
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+	case breakerHalfOpen:
+		if b.probing {
+			b.dropped++
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// Done records the outcome of a request allowed by Allow. Transport errors
+// and 5xx responses are failures. Requests aborted by the context are not
+// counted, but let another probe through.
+func (b *circuitBreaker) Done(ctx context.Context, resp *http.Response, err error) {
+	if b == nil {
+		return
+	}
+
+	if err != nil && ctx.Err() != nil {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	if err != nil || resp.StatusCode >= 500 {
+		b.failure()
+		return
+	}
+	b.success()
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	b.failures++
+	b.probing = false
+	opened := false
+	if b.state == breakerHalfOpen ||
+		(b.state == breakerClosed && b.failures >= b.opt.BreakerThreshold) {
+		opened = b.state == breakerClosed
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(b.opt.BreakerCooldown)
+	}
+	failures := b.failures
+	b.mu.Unlock()
+
+	if opened {
+		b.opt.logger().Printf(
+			"circuit breaker opened after %d consecutive failures, dropping data for %s",
+			failures, b.opt.BreakerCooldown)
+		b.changed(true)
+	}
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	closed := b.state != breakerClosed
+	dropped := b.dropped
+	b.state = breakerClosed
+	b.probing = false
+	b.failures = 0
+	b.dropped = 0
+	b.mu.Unlock()
+
+	if closed {
+		b.opt.logger().Printf("circuit breaker closed, dropped=%d", dropped)
+		b.changed(false)
+	}
+}
+
+func (b *circuitBreaker) changed(open bool) {
+	if b.opt.OnBreakerStateChange != nil {
+		b.opt.OnBreakerStateChange(open)
+	}
+}
+
+// Open reports whether the breaker is open and drops data.
+func (b *circuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerOpen && time.Now().Before(b.openUntil)
+}
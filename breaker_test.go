@@ -0,0 +1,100 @@
+package gobrake_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("circuit breaker", func() {
+	var notifier *gobrake.Notifier
+	var requests, status int32
+	var states []bool
+	var hold chan struct{}
+	var held chan struct{}
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&requests, 0)
+		atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+		states = nil
+		hold, held = nil, nil
+
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if hold != nil {
+				held <- struct{}{}
+				<-hold
+			}
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:        1,
+			ProjectKey:       "key",
+			Host:             server.URL,
+			BreakerThreshold: 2,
+			BreakerCooldown:  50 * time.Millisecond,
+			OnBreakerStateChange: func(open bool) {
+				states = append(states, open)
+			},
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	send := func() error {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		_, err := notifier.SendNotice(notice)
+		return err
+	}
+
+	It("drops notices after consecutive failures until cooldown passes", func() {
+		Expect(send()).To(HaveOccurred())
+		Expect(send()).To(HaveOccurred())
+		Expect(states).To(Equal([]bool{true}))
+
+		err := send()
+		Expect(err).To(MatchError("gobrake: circuit breaker is open (data is dropped)"))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+
+		time.Sleep(60 * time.Millisecond)
+		atomic.StoreInt32(&status, http.StatusCreated)
+		Expect(send()).NotTo(HaveOccurred())
+		Expect(states).To(Equal([]bool{true, false}))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("lets a single probe through after cooldown", func() {
+		Expect(send()).To(HaveOccurred())
+		Expect(send()).To(HaveOccurred())
+		time.Sleep(60 * time.Millisecond)
+
+		atomic.StoreInt32(&status, http.StatusCreated)
+		hold, held = make(chan struct{}), make(chan struct{}, 1)
+		probe := make(chan error, 1)
+		go func() {
+			probe <- send()
+		}()
+		<-held
+
+		err := send()
+		Expect(err).To(MatchError("gobrake: circuit breaker is open (data is dropped)"))
+
+		close(hold)
+		Expect(<-probe).NotTo(HaveOccurred())
+		Expect(states).To(Equal([]bool{true, false}))
+		Expect(send()).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(4)))
+	})
+})
@@ -0,0 +1,21 @@
+//go:build go1.18
+// +build go1.18
+
+package gobrake
+
+import "runtime/debug"
+
+// buildRevision returns the VCS revision stamped into the binary by the
+// go command.
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,10 @@
+//go:build !go1.18
+// +build !go1.18
+
+package gobrake
+
+// buildRevision returns an empty string, because build info does not
+// contain VCS revision before Go 1.18.
+func buildRevision() string {
+	return ""
+}
@@ -0,0 +1,89 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type checkInJSONRequest struct {
+	Name        string    `json:"name"`
+	Environment string    `json:"environment,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// CheckIn reports that the named job or worker is alive. Airbrake
+// detects missed check-ins, so jobs that stop running are noticed even
+// when they never fail loudly.
+func (n *Notifier) CheckIn(name string) error {
+	return n.CheckInContext(context.Background(), name)
+}
+
+// CheckInContext is like CheckIn, but the request is bound to the context.
+func (n *Notifier) CheckInContext(ctx context.Context, name string) error {
+	n.dualWrite("CheckIn", func(s *Notifier) error {
+		return s.CheckInContext(ctx, name)
+	})
+
+	if n.closed() {
+		return errClosed
+	}
+	if n.disabled || n.opt.Errbit {
+		return nil
+	}
+	if n.hostErr != nil {
+		return n.hostErr
+	}
+
+	jsonReq := checkInJSONRequest{
+		Name:        name,
+		Environment: n.opt.Environment,
+		Time:        time.Now().UTC(),
+	}
+	apiURL := fmt.Sprintf("%s/api/v5/projects/%d/check-ins/%s",
+		n.remoteConfig.ErrorHost(n.opt), n.opt.ProjectId, url.PathEscape(name))
+	return sendStats(ctx, n.opt, n.breaker, apiURL, jsonReq)
+}
+
+// StartCheckIn checks in immediately and then every interval until the
+// returned stop function is called or the notifier is closed. A
+// non-positive interval is logged and nothing is scheduled.
+func (n *Notifier) StartCheckIn(name string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		n.opt.logger().Printf("StartCheckIn name=%q failed: invalid interval %s", name, interval)
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if n.closed() {
+				return
+			}
+
+			err := n.CheckIn(name)
+			if err != nil {
+				n.opt.logger().Printf("CheckIn name=%q failed: %s", name, err)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
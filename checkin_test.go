@@ -0,0 +1,105 @@
+package gobrake_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckIn", func() {
+	type checkIn struct {
+		Path        string
+		Name        string `json:"name"`
+		Environment string `json:"environment"`
+	}
+
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+	var logger *bufferLogger
+	var mu sync.Mutex
+	var checkIns []checkIn
+
+	BeforeEach(func() {
+		checkIns = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			var c checkIn
+			err = json.Unmarshal(b, &c)
+			if err != nil {
+				panic(err)
+			}
+			c.Path = req.URL.Path
+
+			mu.Lock()
+			checkIns = append(checkIns, c)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusOK)
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		logger = new(bufferLogger)
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:   1,
+			ProjectKey:  "key",
+			Host:        server.URL,
+			Environment: "production",
+			Logger:      logger,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		// Waits for check-ins of stopped schedules that are still in flight.
+		server.Close()
+	})
+
+	sent := func() []checkIn {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]checkIn(nil), checkIns...)
+	}
+
+	It("reports check-in", func() {
+		Expect(notifier.CheckIn("nightly report")).NotTo(HaveOccurred())
+		Expect(sent()).To(Equal([]checkIn{{
+			Path:        "/api/v5/projects/1/check-ins/nightly report",
+			Name:        "nightly report",
+			Environment: "production",
+		}}))
+	})
+
+	It("checks in on schedule until stopped", func() {
+		stop := notifier.StartCheckIn("worker", 10*time.Millisecond)
+		Eventually(func() int { return len(sent()) }).Should(BeNumerically(">=", 2))
+		stop()
+		stop()
+	})
+
+	It("does not schedule check-ins with invalid interval", func() {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			stop := notifier.StartCheckIn("worker", interval)
+			stop()
+		}
+		Consistently(func() int { return len(sent()) }, 50*time.Millisecond).Should(Equal(0))
+
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		Expect(logger.lines).To(Equal([]string{
+			`StartCheckIn name="worker" failed: invalid interval 0s`,
+			`StartCheckIn name="worker" failed: invalid interval -1s`,
+		}))
+	})
+})
@@ -0,0 +1,88 @@
+package gobrake
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statsClock keeps time for requests stats buckets independently of the
+// host clock: it advances with the monotonic clock from the moment it was
+// created and is synced once with the Date header of an API response.
+// Methods are safe to call on nil clock which means that the host clock
+// is used.
+type statsClock struct {
+	base time.Time
+
+	mu     sync.RWMutex
+	offset time.Duration
+	synced bool
+}
+
+// minClockSkew is the skew that is ignored, because Date header has
+// one second resolution.
+const minClockSkew = 2 * time.Second
+
+func newStatsClock() *statsClock {
+	return &statsClock{
+		base: time.Now(),
+	}
+}
+
+func (c *statsClock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	c.mu.RLock()
+	offset := c.offset
+	c.mu.RUnlock()
+	return c.base.Add(time.Since(c.base) + offset).Round(0)
+}
+
+// Sync sets the clock from the Date header of the first response that
+// has one.
+func (c *statsClock) Sync(resp *http.Response) {
+	if c == nil || resp == nil {
+		return
+	}
+
+	c.mu.RLock()
+	synced := c.synced
+	c.mu.RUnlock()
+	if synced {
+		return
+	}
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.synced {
+		return
+	}
+	offset := date.Sub(c.base.Add(time.Since(c.base)))
+	if offset > -minClockSkew && offset < minClockSkew {
+		offset = 0
+	}
+	c.offset = offset
+	c.synced = true
+}
+
+// Bucket returns the minute bucket of a request that ended at end. The
+// request age is measured with the monotonic clock when possible, and
+// future buckets are clamped to the current minute.
+func (c *statsClock) Bucket(end time.Time) time.Time {
+	if c == nil {
+		return end.UTC().Truncate(time.Minute)
+	}
+
+	now := c.Now()
+	tm := now.Add(-time.Since(end))
+	if tm.After(now) {
+		tm = now
+	}
+	return tm.UTC().Truncate(time.Minute)
+}
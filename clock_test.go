@@ -0,0 +1,59 @@
+package gobrake
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("statsClock", func() {
+	dateResponse := func(tm time.Time) *http.Response {
+		resp := &http.Response{Header: make(http.Header)}
+		resp.Header.Set("Date", tm.UTC().Format(http.TimeFormat))
+		return resp
+	}
+
+	It("uses the host clock when it is nil", func() {
+		var c *statsClock
+		end := time.Now()
+		Expect(c.Bucket(end)).To(Equal(end.UTC().Truncate(time.Minute)))
+	})
+
+	It("syncs with the server Date header once", func() {
+		c := newStatsClock()
+		c.Sync(dateResponse(time.Now().Add(-time.Hour)))
+		c.Sync(dateResponse(time.Now().Add(time.Hour)))
+
+		Expect(c.Now()).To(BeTemporally("~", time.Now().Add(-time.Hour), 2*time.Second))
+		Expect(c.Bucket(time.Now())).To(BeTemporally("~",
+			time.Now().Add(-time.Hour).UTC().Truncate(time.Minute), time.Minute))
+	})
+
+	It("ignores small skew and bad Date headers", func() {
+		c := newStatsClock()
+		c.Sync(&http.Response{Header: http.Header{"Date": {"yesterday"}}})
+		c.Sync(dateResponse(time.Now()))
+
+		Expect(c.offset).To(BeZero())
+		Expect(c.synced).To(BeTrue())
+	})
+
+	It("clamps future buckets to the current minute", func() {
+		c := newStatsClock()
+		bucket := c.Bucket(time.Now().Add(10 * time.Minute))
+		Expect(bucket).To(BeTemporally("<=", time.Now()))
+		Expect(bucket).To(BeTemporally("~", time.Now().UTC().Truncate(time.Minute), time.Minute))
+	})
+
+	It("is enabled by ClockSkewCorrection", func() {
+		opt := &NotifierOptions{ClockSkewCorrection: true}
+		opt.init()
+		Expect(opt.clock).NotTo(BeNil())
+
+		opt.clock.Sync(dateResponse(time.Now().Add(2 * time.Hour)))
+		key := opt.routeKey(&RequestInfo{Method: "GET", Route: "/", End: time.Now()})
+		Expect(key.Time()).To(BeTemporally(">", time.Now().Add(time.Hour)))
+	})
+})
@@ -0,0 +1,57 @@
+package gobrake_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CloseWithTimeout", func() {
+	var notifier *gobrake.Notifier
+	var server *httptest.Server
+	var unblock chan struct{}
+
+	BeforeEach(func() {
+		unblock = make(chan struct{})
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			<-unblock
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		close(unblock)
+		server.Close()
+	})
+
+	It("returns within the budget abandoning pending data", func() {
+		notifier.Notify("hello", nil)
+		start := time.Now()
+		err := notifier.NotifyRequest(&gobrake.RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = notifier.CloseWithTimeout(50 * time.Millisecond)
+		Expect(err).To(MatchError(
+			"gobrake: flush aborted: context deadline exceeded (abandoned notices=1 routes=1)"))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+})
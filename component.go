@@ -0,0 +1,14 @@
+package gobrake
+
+func newComponentActionFilter(fn func(*Notice) (string, string)) func(*Notice) *Notice {
+	return func(notice *Notice) *Notice {
+		component, action := fn(notice)
+		if component != "" {
+			notice.Context["component"] = component
+		}
+		if action != "" {
+			notice.Context["action"] = action
+		}
+		return notice
+	}
+}
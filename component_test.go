@@ -0,0 +1,45 @@
+package gobrake_test
+
+import (
+	"errors"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newComponentNotice() *gobrake.Notice {
+	return gobrake.NewNotice(errors.New("failed"), nil, 0)
+}
+
+var _ = Describe("component and action", func() {
+	It("are detected from the top in-app frame", func() {
+		notice := newComponentNotice()
+		Expect(notice.Context["component"]).To(Equal("github.com/airbrake/gobrake_test"))
+		Expect(notice.Context["action"]).To(Equal("newComponentNotice"))
+	})
+
+	It("can be overridden with ComponentActionFunc", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			DisableRemoteConfig: true,
+			ComponentActionFunc: func(notice *gobrake.Notice) (string, string) {
+				return "billing", ""
+			},
+		})
+		defer notifier.Close()
+
+		var sentNotice *gobrake.Notice
+		notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+			sentNotice = notice
+			return nil
+		})
+
+		_, err := notifier.SendNotice(newComponentNotice())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Context["component"]).To(Equal("billing"))
+		Expect(sentNotice.Context["action"]).To(Equal("newComponentNotice"))
+	})
+})
@@ -0,0 +1,81 @@
+package gobrake_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ctxKey struct{}
+
+var _ = Describe("Notifier with context", func() {
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+	var requests int
+
+	BeforeEach(func() {
+		requests = 0
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		server.Close()
+	})
+
+	It("passes context to context filters", func() {
+		var value interface{}
+		notifier.AddContextFilter(func(ctx context.Context, notice *gobrake.Notice) *gobrake.Notice {
+			value = ctx.Value(ctxKey{})
+			return notice
+		})
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+		notice := notifier.Notice("hello", nil, 0)
+		id, err := notifier.SendNoticeContext(ctx, notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("123"))
+		Expect(value).To(Equal("value"))
+	})
+
+	It("does not send notice when context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		notice := notifier.Notice("hello", nil, 0)
+		_, err := notifier.SendNoticeContext(ctx, notice)
+		Expect(err).To(HaveOccurred())
+		Expect(requests).To(Equal(0))
+	})
+
+	It("sends notice with context detached from cancellation", func() {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+		cancel()
+
+		detached := gobrake.WithoutCancel(ctx)
+		Expect(detached.Err()).NotTo(HaveOccurred())
+		Expect(detached.Done()).To(BeNil())
+		Expect(detached.Value(ctxKey{})).To(Equal("value"))
+
+		notice := notifier.Notice("hello", nil, 0)
+		_, err := notifier.SendNoticeContext(detached, notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal(1))
+	})
+})
@@ -0,0 +1,227 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultCrashFlushTimeout = 3 * time.Second
+	maxCrashGoroutines       = 100
+)
+
+// DefaultCrashSignals are the signals handled by NewCrashHandler when no
+// signals are passed. They are fatal signals that are sent when the
+// process is stuck or aborts, so they are reported with goroutine stacks.
+// SIGTERM is not included, because it is a routine shutdown request that
+// applications usually handle themselves; pass it explicitly to flush on
+// termination.
+var DefaultCrashSignals = []os.Signal{syscall.SIGQUIT, syscall.SIGABRT}
+
+// CrashHandler reports crashes as a last gasp before the process dies:
+// panics escaping Run, fatal signals and, on Go 1.23+, fatal runtime
+// errors captured with CaptureFatalErrors. Buffered notices and stats are
+// flushed synchronously with a short deadline.
+type CrashHandler struct {
+	notifier *Notifier
+	timeout  time.Duration
+
+	signals chan os.Signal
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewCrashHandler installs handlers of the signals, which default to
+// DefaultCrashSignals. When a signal is received, buffered notices and
+// stats are flushed within timeout (default 3s) and the signal is raised
+// again with the default behavior, so the process exits as it would
+// without the handler. SIGQUIT and other signals except SIGTERM and
+// SIGINT are also reported as critical notices with goroutine stacks.
+// Signals the application handles itself should not be passed, because
+// they are raised again.
+func (n *Notifier) NewCrashHandler(timeout time.Duration, signals ...os.Signal) *CrashHandler {
+	if timeout <= 0 {
+		timeout = defaultCrashFlushTimeout
+	}
+	if len(signals) == 0 {
+		signals = DefaultCrashSignals
+	}
+
+	h := &CrashHandler{
+		notifier: n,
+		timeout:  timeout,
+		signals:  make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+	}
+	signal.Notify(h.signals, signals...)
+	go h.watch()
+	return h
+}
+
+// Stop uninstalls signal handlers.
+func (h *CrashHandler) Stop() {
+	h.once.Do(func() {
+		signal.Stop(h.signals)
+		close(h.done)
+	})
+}
+
+func (h *CrashHandler) watch() {
+	select {
+	case sig := <-h.signals:
+		h.handleSignal(sig)
+	case <-h.done:
+	}
+}
+
+func (h *CrashHandler) handleSignal(sig os.Signal) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	if sig != syscall.SIGTERM && sig != os.Interrupt {
+		notice := h.notifier.Notice(fmt.Sprintf("received signal %s", sig), nil, 0)
+		notice.Errors[0].Type = "signal"
+		notice.Context["severity"] = "critical"
+		notice.Params["goroutines"] = goroutineStacks()
+		h.notifier.SendNoticeContext(ctx, notice)
+	}
+	h.flush(ctx)
+
+	h.Stop()
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		err = p.Signal(sig)
+		if err == nil {
+			return
+		}
+	}
+	os.Exit(2)
+}
+
+// Run calls fn, e.g. the body of main, and reports a panic escaping it.
+// The panic notice is sent synchronously and buffered notices and stats
+// are flushed before the panic is re-raised.
+func (h *CrashHandler) Run(fn func()) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+		defer cancel()
+
+		n := h.notifier
+		n.spoolPanic(v)
+		notice := n.Notice(v, nil, 3)
+		notice.Context["severity"] = "critical"
+		n.SendNoticeContext(ctx, notice)
+		h.flush(ctx)
+
+		panic(v)
+	}()
+	fn()
+}
+
+func (h *CrashHandler) flush(ctx context.Context) {
+	err := h.notifier.FlushContext(ctx)
+	if err != nil {
+		h.notifier.opt.logger().Printf("CrashHandler.flush failed: %s", err)
+	}
+}
+
+// goroutineStacks returns stacks of all goroutines, one per element.
+func goroutineStacks() []interface{} {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	var stacks []interface{}
+	for _, s := range strings.Split(string(buf), "\n\n") {
+		if len(stacks) == maxCrashGoroutines {
+			break
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			stacks = append(stacks, s)
+		}
+	}
+	return stacks
+}
+
+// parseCrashOutput parses the output the runtime writes when the process
+// crashes, e.g. "panic: ..." or "fatal error: ...", followed by goroutine
+// stacks. The backtrace is taken from the first goroutine.
+func parseCrashOutput(s string) (typ, message string, backtrace []StackFrame) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	first := strings.TrimSpace(lines[0])
+
+	typ = "crash"
+	message = first
+	for _, prefix := range []string{"panic", "fatal error"} {
+		if strings.HasPrefix(first, prefix+": ") {
+			typ = prefix
+			message = strings.TrimPrefix(first, prefix+": ")
+			break
+		}
+	}
+
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "goroutine ") {
+			start = i + 1
+			break
+		}
+	}
+	if start == -1 {
+		return typ, message, nil
+	}
+
+	backtrace = make([]StackFrame, 0)
+	for i := start; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		loc := strings.TrimSpace(lines[i+1])
+		if fn == "" || !strings.HasPrefix(lines[i+1], "\t") {
+			break
+		}
+
+		fn = strings.TrimPrefix(fn, "created by ")
+		if ind := strings.Index(fn, " in goroutine "); ind > 0 {
+			fn = fn[:ind]
+		} else if ind := strings.LastIndex(fn, "("); ind > 0 {
+			fn = fn[:ind]
+		}
+		if ind := strings.LastIndex(loc, " +0x"); ind > 0 {
+			loc = loc[:ind]
+		}
+		frame := StackFrame{File: loc}
+		if ind := strings.LastIndex(loc, ":"); ind > 0 {
+			if line, err := strconv.Atoi(loc[ind+1:]); err == nil {
+				frame.File, frame.Line = loc[:ind], line
+			}
+		}
+		_, frame.Func = splitPackageFuncName(fn)
+		backtrace = append(backtrace, frame)
+	}
+	return typ, message, backtrace
+}
+
+// newCrashNotice returns a critical notice of the crash output saved by
+// a previous process.
+func newCrashNotice(output string) *Notice {
+	typ, message, backtrace := parseCrashOutput(output)
+
+	notice := NewNotice(message, nil, 0)
+	notice.Errors[0].Type = typ
+	if backtrace != nil {
+		notice.Errors[0].Backtrace = backtrace
+	}
+	notice.Context["severity"] = "critical"
+	notice.Params["crashOutput"] = output
+	return notice
+}
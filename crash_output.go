@@ -0,0 +1,41 @@
+//go:build go1.23
+// +build go1.23
+
+package gobrake
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+)
+
+// CaptureFatalErrors makes the runtime write fatal errors that can not be
+// recovered, e.g. unrecovered panics in any goroutine, concurrent map
+// writes or faults in cgo code, to the file. A crash saved by a previous
+// process is reported as a critical notice first and the file is truncated
+// only when the notice is sent. It requires Go 1.23.
+func (h *CrashHandler) CaptureFatalErrors(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if len(b) > 0 {
+		_, err := h.notifier.SendNotice(newCrashNotice(string(b)))
+		if err != nil {
+			h.notifier.opt.logger().Printf("CrashHandler.CaptureFatalErrors failed "+
+				"reporting crash file=%q: %s", filename, err)
+			// Keep the crash so the next process can report it.
+			flag = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+		}
+	}
+
+	f, err := os.OpenFile(filename, flag, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The runtime duplicates the file descriptor.
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
@@ -0,0 +1,12 @@
+//go:build !go1.23
+// +build !go1.23
+
+package gobrake
+
+import "errors"
+
+// CaptureFatalErrors returns an error, because the runtime can not write
+// fatal errors to a file before Go 1.23.
+func (h *CrashHandler) CaptureFatalErrors(filename string) error {
+	return errors.New("gobrake: capturing fatal errors requires Go 1.23")
+}
@@ -0,0 +1,96 @@
+//go:build go1.23
+// +build go1.23
+
+package gobrake
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CrashHandler.CaptureFatalErrors", func() {
+	It("reports the crash saved by a previous process", func() {
+		var requests int
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
+		})
+		defer notifier.Close()
+
+		var sentNotice *Notice
+		notifier.AddFilter(func(notice *Notice) *Notice {
+			sentNotice = notice
+			return notice
+		})
+
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "crash.log")
+		Expect(ioutil.WriteFile(filename, []byte(crashOutput), 0600)).To(Succeed())
+
+		h := notifier.NewCrashHandler(0)
+		defer h.Stop()
+		Expect(h.CaptureFatalErrors(filename)).To(Succeed())
+		defer debug.SetCrashOutput(nil, debug.CrashOptions{})
+
+		Expect(requests).To(Equal(1))
+		Expect(sentNotice.Errors[0].Type).To(Equal("panic"))
+		Expect(sentNotice.Errors[0].Backtrace[0].File).To(Equal("/app/worker.go"))
+		Expect(sentNotice.Context["severity"]).To(Equal("critical"))
+
+		b, err := ioutil.ReadFile(filename)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeEmpty())
+	})
+
+	It("keeps the crash when it can not be reported", func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
+			Logger:              log.New(ioutil.Discard, "", 0),
+		})
+		defer notifier.Close()
+
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "crash.log")
+		Expect(ioutil.WriteFile(filename, []byte(crashOutput), 0600)).To(Succeed())
+
+		h := notifier.NewCrashHandler(0)
+		defer h.Stop()
+		Expect(h.CaptureFatalErrors(filename)).To(Succeed())
+		defer debug.SetCrashOutput(nil, debug.CrashOptions{})
+
+		b, err := ioutil.ReadFile(filename)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(crashOutput))
+	})
+})
@@ -0,0 +1,142 @@
+package gobrake
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const crashOutput = `panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x47e2c1]
+
+goroutine 7 [running]:
+main.(*worker).run(0x0, 0xc000012345)
+	/app/worker.go:42 +0x21
+created by main.main in goroutine 1
+	/app/main.go:17 +0x6a
+`
+
+var _ = Describe("CrashHandler", func() {
+	var server *httptest.Server
+	var notifier *Notifier
+	var mu sync.Mutex
+	var notices []*Notice
+	var routes int
+
+	BeforeEach(func() {
+		notices = nil
+		routes = 0
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, _ := ioutil.ReadAll(req.Body)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if req.Method == "PUT" {
+				routes++
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			notice := new(Notice)
+			json.Unmarshal(b, notice)
+			notices = append(notices, notice)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
+		})
+	})
+
+	AfterEach(func() {
+		notifier.Close()
+		server.Close()
+	})
+
+	It("reports a panic escaping Run and flushes before re-panicking", func() {
+		h := notifier.NewCrashHandler(time.Second)
+		defer h.Stop()
+
+		start := time.Now()
+		notifier.NotifyRequest(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		notifier.SendNoticeAsync(notifier.Notice(errors.New("buffered"), nil, 0))
+
+		Expect(func() {
+			h.Run(func() {
+				panic("boom")
+			})
+		}).To(Panic())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(notices).To(HaveLen(2))
+		var messages []string
+		for _, notice := range notices {
+			messages = append(messages, notice.Errors[0].Message)
+		}
+		Expect(messages).To(ConsistOf("buffered", "boom"))
+		Expect(routes).To(Equal(1))
+	})
+
+	It("does nothing when fn returns", func() {
+		h := notifier.NewCrashHandler(0)
+		h.Stop()
+		h.Stop()
+
+		var called bool
+		h.Run(func() {
+			called = true
+		})
+		Expect(called).To(BeTrue())
+		Expect(h.timeout).To(Equal(defaultCrashFlushTimeout))
+	})
+
+	It("does not handle SIGTERM by default", func() {
+		Expect(DefaultCrashSignals).To(ConsistOf(syscall.SIGQUIT, syscall.SIGABRT))
+		Expect(DefaultCrashSignals).NotTo(ContainElement(syscall.SIGTERM))
+	})
+})
+
+var _ = Describe("parseCrashOutput", func() {
+	It("parses panic message and the first goroutine", func() {
+		typ, message, backtrace := parseCrashOutput(crashOutput)
+		Expect(typ).To(Equal("panic"))
+		Expect(message).To(Equal("runtime error: invalid memory address or nil pointer dereference"))
+		Expect(backtrace).To(Equal([]StackFrame{
+			{File: "/app/worker.go", Line: 42, Func: "(*worker).run"},
+			{File: "/app/main.go", Line: 17, Func: "main"},
+		}))
+	})
+
+	It("parses fatal errors", func() {
+		typ, message, _ := parseCrashOutput("fatal error: concurrent map writes\n")
+		Expect(typ).To(Equal("fatal error"))
+		Expect(message).To(Equal("concurrent map writes"))
+	})
+
+	It("keeps unknown output as message", func() {
+		typ, message, backtrace := parseCrashOutput("SIGABRT: abort\nPC=0x0")
+		Expect(typ).To(Equal("crash"))
+		Expect(message).To(Equal("SIGABRT: abort"))
+		Expect(backtrace).To(BeNil())
+	})
+})
@@ -0,0 +1,114 @@
+package gobrake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	defaultCrashLoopThreshold = 5
+	defaultCrashLoopWindow    = 10 * time.Minute
+)
+
+// crashLoopState is persisted in NotifierOptions.CrashLoopFile between
+// process restarts.
+type crashLoopState struct {
+	Starts     []int64 `json:"starts"`
+	LastPanic  string  `json:"last_panic,omitempty"`
+	ReportedAt int64   `json:"reported_at,omitempty"`
+}
+
+func (opt *NotifierOptions) loadCrashLoopState() *crashLoopState {
+	state := new(crashLoopState)
+	b, err := ioutil.ReadFile(opt.CrashLoopFile)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return new(crashLoopState)
+	}
+	return state
+}
+
+func (opt *NotifierOptions) saveCrashLoopState(state *crashLoopState) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(opt.CrashLoopFile, b, 0600)
+	if err != nil {
+		opt.logger().Printf("saveCrashLoopState file=%q failed: %s", opt.CrashLoopFile, err)
+	}
+}
+
+// recordStart records the process start in CrashLoopFile. Starts older
+// than CrashLoopWindow are dropped.
+func (n *Notifier) recordStart() {
+	now := time.Now()
+	since := now.Add(-n.opt.CrashLoopWindow).Unix()
+
+	state := n.opt.loadCrashLoopState()
+	starts := state.Starts[:0]
+	for _, ts := range state.Starts {
+		if ts >= since {
+			starts = append(starts, ts)
+		}
+	}
+	state.Starts = append(starts, now.Unix())
+	n.opt.saveCrashLoopState(state)
+}
+
+// ReportCrashLoop sends a single notice when the process restarted more
+// than CrashLoopThreshold times within CrashLoopWindow. Another notice is
+// not sent until the window passes. The notice goes through the notifier
+// filters, so it should be called at startup after filters are added.
+// It reports whether a crash loop was detected.
+func (n *Notifier) ReportCrashLoop() bool {
+	if n.opt.CrashLoopFile == "" {
+		return false
+	}
+
+	now := time.Now()
+	since := now.Add(-n.opt.CrashLoopWindow).Unix()
+
+	state := n.opt.loadCrashLoopState()
+	var restarts int
+	for _, ts := range state.Starts {
+		if ts >= since {
+			restarts++
+		}
+	}
+	restarts-- // the current start
+	if restarts <= n.opt.CrashLoopThreshold || state.ReportedAt >= since {
+		return false
+	}
+
+	notice := n.Notice(fmt.Errorf(
+		"gobrake: crash-loop suspected: %d restarts within %s",
+		restarts, n.opt.CrashLoopWindow), nil, 0)
+	notice.Context["severity"] = "critical"
+	if state.LastPanic != "" {
+		notice.Params["lastPanic"] = state.LastPanic
+	}
+	n.SendNoticeAsync(notice)
+
+	// The panic is reported, so it is not attached to the next crash loop.
+	state.LastPanic = ""
+	state.ReportedAt = now.Unix()
+	n.opt.saveCrashLoopState(state)
+	return true
+}
+
+// spoolPanic saves the panic so it can be attached to a crash-loop notice
+// sent after the process restarts.
+func (n *Notifier) spoolPanic(v interface{}) {
+	if n.opt.CrashLoopFile == "" {
+		return
+	}
+
+	state := n.opt.loadCrashLoopState()
+	state.LastPanic = fmt.Sprint(v)
+	n.opt.saveCrashLoopState(state)
+}
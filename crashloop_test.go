@@ -0,0 +1,129 @@
+package gobrake_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("crash loop detection", func() {
+	var server *httptest.Server
+	var dir string
+	var mu sync.Mutex
+	var notices []*gobrake.Notice
+
+	BeforeEach(func() {
+		notices = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			var notice *gobrake.Notice
+			err = json.Unmarshal(b, &notice)
+			if err != nil {
+				panic(err)
+			}
+
+			mu.Lock()
+			notices = append(notices, notice)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		var err error
+		dir, err = ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	newNotifier := func() *gobrake.Notifier {
+		return gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:          1,
+			ProjectKey:         "key",
+			Host:               server.URL,
+			CrashLoopFile:      filepath.Join(dir, "crashloop.json"),
+			CrashLoopThreshold: 2,
+		})
+	}
+
+	start := func() *gobrake.Notifier {
+		notifier := newNotifier()
+		notifier.ReportCrashLoop()
+		return notifier
+	}
+
+	It("sends a single notice with the last panic", func() {
+		notifier := start()
+		func() {
+			defer func() {
+				recover()
+			}()
+			defer notifier.NotifyOnPanic()
+			panic("boom")
+		}()
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+
+		for i := 0; i < 4; i++ {
+			Expect(start().Close()).NotTo(HaveOccurred())
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(notices).To(HaveLen(2))
+		Expect(notices[0].Errors[0].Message).To(Equal("boom"))
+		Expect(notices[1].Errors[0].Message).To(Equal(
+			"gobrake: crash-loop suspected: 3 restarts within 10m0s"))
+		Expect(notices[1].Params["lastPanic"]).To(Equal("boom"))
+	})
+	It("applies filters added after construction and forgets the reported panic", func() {
+		notifier := start()
+		func() {
+			defer func() {
+				recover()
+			}()
+			defer notifier.NotifyOnPanic()
+			panic("boom")
+		}()
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			Expect(start().Close()).NotTo(HaveOccurred())
+		}
+
+		notifier = newNotifier()
+		notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+			notice.Params["lastPanic"] = "[Filtered]"
+			return notice
+		})
+		Expect(notifier.ReportCrashLoop()).To(BeTrue())
+		Expect(notifier.ReportCrashLoop()).To(BeFalse())
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+
+		mu.Lock()
+		Expect(notices).To(HaveLen(2))
+		Expect(notices[1].Params["lastPanic"]).To(Equal("[Filtered]"))
+		mu.Unlock()
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "crashloop.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).NotTo(ContainSubstring("last_panic"))
+	})
+})
@@ -0,0 +1,295 @@
+package gobrake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultCSPReportsPerMinute = 60
+	maxCSPReportLen            = 64 * 1024
+	maxCSPSampleLen            = 40
+)
+
+var cspNonceRe = regexp.MustCompile(`'nonce-[^']*'`)
+
+// cspReport is a violation report sent by browsers with
+// Content-Type application/csp-report.
+type cspReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		ScriptSample       string `json:"script-sample"`
+		StatusCode         int    `json:"status-code"`
+	} `json:"csp-report"`
+}
+
+// browserReport is a report sent by browsers with the Reporting API and
+// Content-Type application/reports+json.
+type browserReport struct {
+	Type      string                 `json:"type"`
+	URL       string                 `json:"url"`
+	UserAgent string                 `json:"user_agent"`
+	Body      map[string]interface{} `json:"body"`
+}
+
+// CSPReportHandler is an http.Handler that accepts CSP violation reports
+// and Reporting API payloads from browsers, e.g. as the report-uri or
+// report-to endpoint, and sends them to Airbrake as notices. Query
+// strings of reported URLs and CSP nonces are scrubbed.
+type CSPReportHandler struct {
+	notifier *Notifier
+	limit    int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+var _ http.Handler = (*CSPReportHandler)(nil)
+
+// NewCSPReportHandler returns a handler that sends at most
+// reportsPerMinute reports as notices and drops the rest. Default is 60
+// reports per minute.
+func NewCSPReportHandler(notifier *Notifier, reportsPerMinute int) *CSPReportHandler {
+	if reportsPerMinute <= 0 {
+		reportsPerMinute = defaultCSPReportsPerMinute
+	}
+	return &CSPReportHandler{
+		notifier: notifier,
+		limit:    reportsPerMinute,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *CSPReportHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxCSPReportLen))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	notices, err := parseBrowserReports(b, req.UserAgent())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, notice := range notices {
+		if !h.allow() {
+			break
+		}
+		h.notifier.SendNoticeAsync(notice)
+	}
+
+	// Browsers retry failed Reporting API deliveries, so dropped reports
+	// are acknowledged too.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CSPReportHandler) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(h.windowStart) >= time.Minute {
+		h.windowStart = now
+		h.count = 0
+	}
+	if h.count >= h.limit {
+		return false
+	}
+	h.count++
+	return true
+}
+
+func parseBrowserReports(b []byte, userAgent string) ([]*Notice, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, errors.New("gobrake: empty browser report")
+	}
+
+	if b[0] == '[' {
+		var reports []browserReport
+		if err := json.Unmarshal(b, &reports); err != nil {
+			return nil, err
+		}
+
+		notices := make([]*Notice, 0, len(reports))
+		for i := range reports {
+			notices = append(notices, reports[i].notice())
+		}
+		return notices, nil
+	}
+
+	var report cspReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return nil, err
+	}
+	return []*Notice{report.notice(userAgent)}, nil
+}
+
+func (r *cspReport) notice(userAgent string) *Notice {
+	rep := &r.Report
+
+	directive := rep.EffectiveDirective
+	if directive == "" {
+		directive = rep.ViolatedDirective
+	}
+	blocked := scrubURL(rep.BlockedURI)
+
+	notice := newBrowserNotice("csp-violation",
+		fmt.Sprintf("%s blocked by %s", blocked, directive),
+		scrubURL(rep.DocumentURI), userAgent)
+	notice.setBrowserSource(rep.SourceFile, rep.LineNumber)
+	notice.Params["report"] = map[string]interface{}{
+		"documentURL":        scrubURL(rep.DocumentURI),
+		"referrer":           scrubURL(rep.Referrer),
+		"blockedURL":         blocked,
+		"violatedDirective":  rep.ViolatedDirective,
+		"effectiveDirective": rep.EffectiveDirective,
+		"originalPolicy":     scrubPolicy(rep.OriginalPolicy),
+		"disposition":        rep.Disposition,
+		"sourceFile":         scrubURL(rep.SourceFile),
+		"lineNumber":         rep.LineNumber,
+		"columnNumber":       rep.ColumnNumber,
+		"sample":             truncateSample(rep.ScriptSample),
+		"statusCode":         rep.StatusCode,
+	}
+	return notice
+}
+
+func (r *browserReport) notice() *Notice {
+	typ := r.Type
+	if typ == "" {
+		typ = "report"
+	}
+
+	body := make(map[string]interface{}, len(r.Body))
+	for k, v := range r.Body {
+		s, ok := v.(string)
+		if !ok {
+			body[k] = v
+			continue
+		}
+		switch k {
+		case "documentURL", "blockedURL", "referrer", "sourceFile", "url":
+			s = scrubURL(s)
+		case "originalPolicy":
+			s = scrubPolicy(s)
+		case "sample":
+			s = truncateSample(s)
+		}
+		body[k] = s
+	}
+
+	var message string
+	if typ == "csp-violation" {
+		message = fmt.Sprintf("%v blocked by %v", body["blockedURL"], body["effectiveDirective"])
+	} else if msg, ok := body["message"].(string); ok {
+		message = msg
+	} else {
+		message = typ
+	}
+
+	notice := newBrowserNotice(typ, message, scrubURL(r.URL), r.UserAgent)
+	source, _ := body["sourceFile"].(string)
+	line, _ := body["lineNumber"].(float64)
+	notice.setBrowserSource(source, int(line))
+	notice.Params["report"] = body
+	return notice
+}
+
+func newBrowserNotice(typ, message, pageURL, userAgent string) *Notice {
+	notice := &Notice{
+		Errors: []Error{{
+			Type:      typ,
+			Message:   message,
+			Backtrace: []StackFrame{},
+		}},
+		Context: make(map[string]interface{}),
+		Env:     make(map[string]interface{}),
+		Session: make(map[string]interface{}),
+		Params:  make(map[string]interface{}),
+
+		cause: message,
+	}
+	for k, v := range getDefaultContext() {
+		notice.Context[k] = v
+	}
+	notice.Context["component"] = "browser"
+	notice.Context["action"] = typ
+	notice.Context["severity"] = "warning"
+	if pageURL != "" {
+		notice.Context["url"] = pageURL
+	}
+	if userAgent != "" {
+		notice.Context["userAgent"] = userAgent
+	}
+	return notice
+}
+
+func (n *Notice) setBrowserSource(file string, line int) {
+	if file == "" {
+		return
+	}
+	n.Errors[0].Backtrace = []StackFrame{{
+		File: scrubURL(file),
+		Line: line,
+	}}
+}
+
+// scrubURL removes credentials, query and fragment from the URL which
+// often contain tokens. Keywords like inline or eval are kept as is.
+func scrubURL(s string) string {
+	if !strings.Contains(s, ":") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+func scrubPolicy(s string) string {
+	return cspNonceRe.ReplaceAllString(s, "'nonce-[Filtered]'")
+}
+
+func truncateSample(s string) string {
+	if len(s) <= maxCSPSampleLen {
+		return s
+	}
+	n := maxCSPSampleLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
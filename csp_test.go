@@ -0,0 +1,122 @@
+package gobrake_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSPReportHandler", func() {
+	var notifier *gobrake.Notifier
+	var mu sync.Mutex
+	var sentNotices []*gobrake.Notice
+
+	BeforeEach(func() {
+		sentNotices = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+			var notice *gobrake.Notice
+			err = json.Unmarshal(b, &notice)
+			if err != nil {
+				panic(err)
+			}
+
+			mu.Lock()
+			sentNotices = append(sentNotices, notice)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	post := func(h http.Handler, contentType, body string) int {
+		req := httptest.NewRequest("POST", "/csp", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		notifier.Flush()
+		return w.Code
+	}
+
+	It("converts CSP violation reports into scrubbed notices", func() {
+		h := gobrake.NewCSPReportHandler(notifier, 0)
+		code := post(h, "application/csp-report", `{"csp-report": {
+			"document-uri": "https://example.com/page?token=secret",
+			"blocked-uri": "https://evil.example.com/x.js?a=1",
+			"effective-directive": "script-src-elem",
+			"original-policy": "script-src 'nonce-abc123'",
+			"source-file": "https://example.com/app.js",
+			"line-number": 10
+		}}`)
+		Expect(code).To(Equal(http.StatusNoContent))
+
+		Expect(sentNotices).To(HaveLen(1))
+		notice := sentNotices[0]
+		Expect(notice.Errors[0].Type).To(Equal("csp-violation"))
+		Expect(notice.Errors[0].Message).To(Equal(
+			"https://evil.example.com/x.js blocked by script-src-elem"))
+		Expect(notice.Errors[0].Backtrace[0].File).To(Equal("https://example.com/app.js"))
+		Expect(notice.Errors[0].Backtrace[0].Line).To(Equal(10))
+		Expect(notice.Context["component"]).To(Equal("browser"))
+		Expect(notice.Context["url"]).To(Equal("https://example.com/page"))
+		Expect(notice.Context["userAgent"]).To(Equal("Mozilla/5.0"))
+
+		report := notice.Params["report"].(map[string]interface{})
+		Expect(report["originalPolicy"]).To(Equal("script-src 'nonce-[Filtered]'"))
+	})
+
+	It("converts Reporting API payloads and rate limits them", func() {
+		h := gobrake.NewCSPReportHandler(notifier, 1)
+		code := post(h, "application/reports+json", `[{
+			"type": "deprecation",
+			"url": "https://example.com/?q=1",
+			"user_agent": "Mozilla/5.0",
+			"body": {"id": "Foo", "message": "Foo is deprecated"}
+		}, {
+			"type": "csp-violation",
+			"url": "https://example.com/",
+			"body": {"blockedURL": "inline", "effectiveDirective": "style-src"}
+		}]`)
+		Expect(code).To(Equal(http.StatusNoContent))
+
+		Expect(sentNotices).To(HaveLen(1))
+		notice := sentNotices[0]
+		Expect(notice.Errors[0].Type).To(Equal("deprecation"))
+		Expect(notice.Errors[0].Message).To(Equal("Foo is deprecated"))
+		Expect(notice.Context["url"]).To(Equal("https://example.com/"))
+	})
+
+	It("rejects invalid requests", func() {
+		h := gobrake.NewCSPReportHandler(notifier, 0)
+		Expect(post(h, "application/csp-report", "not json")).To(Equal(http.StatusBadRequest))
+
+		req := httptest.NewRequest("GET", "/csp", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
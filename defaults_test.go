@@ -0,0 +1,87 @@
+package gobrake_test
+
+import (
+	"errors"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("default params and context", func() {
+	var notifier *gobrake.Notifier
+	var sentNotice *gobrake.Notice
+
+	BeforeEach(func() {
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost:1",
+			DefaultContext: map[string]interface{}{
+				"region":   "eu-west-1",
+				"hostname": "web-1",
+			},
+		})
+		notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+			sentNotice = notice
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		notifier.Close()
+	})
+
+	It("merges default context into every notice", func() {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notice.Context["hostname"] = "web-2"
+
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Context["region"]).To(Equal("eu-west-1"))
+		Expect(sentNotice.Context["hostname"]).To(Equal("web-2"))
+	})
+
+	It("overrides library default context", func() {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Context["hostname"]).To(Equal("web-1"))
+	})
+
+	It("overrides library default context of notices created with NewNotice", func() {
+		notice := gobrake.NewNotice(errors.New("hello"), nil, 0)
+		Expect(notice.Context).To(HaveKey("hostname"))
+
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Context["hostname"]).To(Equal("web-1"))
+		Expect(sentNotice.Context["region"]).To(Equal("eu-west-1"))
+	})
+
+	It("merges default params into every notice", func() {
+		notifier.AddDefaultParam("version", "1.2.3")
+		notifier.AddDefaultParam("pod", "web-7d9f")
+
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notice.Params["pod"] = "worker-1"
+
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Params["version"]).To(Equal("1.2.3"))
+		Expect(sentNotice.Params["pod"]).To(Equal("worker-1"))
+	})
+
+	It("handles notices without params", func() {
+		notifier.AddDefaultParam("version", "1.2.3")
+
+		_, err := notifier.SendNotice(&gobrake.Notice{
+			Errors: []gobrake.Error{{Type: "error", Message: "hello"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentNotice.Params["version"]).To(Equal("1.2.3"))
+		Expect(sentNotice.Context["region"]).To(Equal("eu-west-1"))
+	})
+})
@@ -0,0 +1,85 @@
+package gobrake
+
+import (
+	"context"
+)
+
+// DualWriteNotifier returns the notifier created from DualWrite options
+// or nil. Filters are not shared, so add them to it too when needed.
+func (n *Notifier) DualWriteNotifier() *Notifier {
+	return n.secondary
+}
+
+// dualWrite calls fn with the DualWrite notifier. Its errors are logged
+// and never returned, so the second backend can not fail the first one.
+func (n *Notifier) dualWrite(name string, fn func(*Notifier) error) {
+	if n.secondary == nil {
+		return
+	}
+	err := fn(n.secondary)
+	if err != nil {
+		n.opt.logger().Printf("dualWrite.%s failed: %s", name, err)
+	}
+}
+
+// dualWriteNotice mirrors the notice after filters and rules are applied,
+// so redacted values and ignored notices never reach the second backend.
+func (n *Notifier) dualWriteNotice(ctx context.Context, notice *Notice) {
+	if n.secondary == nil {
+		return
+	}
+	n.secondary.SendNoticeAsyncContext(WithoutCancel(ctx), notice.clone())
+}
+
+// clone returns a deep copy of the notice that can be changed by filters
+// concurrently with the original.
+func (n *Notice) clone() *Notice {
+	cp := &Notice{
+		Errors:  make([]Error, len(n.Errors)),
+		Context: copyMap(n.Context),
+		Env:     copyMap(n.Env),
+		Session: copyMap(n.Session),
+		Params:  copyMap(n.Params),
+
+		cause: n.cause,
+	}
+	for i, e := range n.Errors {
+		e.Backtrace = append([]StackFrame(nil), e.Backtrace...)
+		cp.Errors[i] = e
+	}
+	return cp
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		cp[k] = copyValue(v)
+	}
+	return cp
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyMap(v)
+	case map[string]string:
+		cp := make(map[string]string, len(v))
+		for k, s := range v {
+			cp[k] = s
+		}
+		return cp
+	case []interface{}:
+		cp := make([]interface{}, len(v))
+		for i, el := range v {
+			cp[i] = copyValue(el)
+		}
+		return cp
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,108 @@
+package gobrake_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DualWrite", func() {
+	var notifier *gobrake.Notifier
+	var mu sync.Mutex
+	var secondaryPaths []string
+	var secondaryNotices []*gobrake.Notice
+
+	BeforeEach(func() {
+		secondaryPaths = nil
+		secondaryNotices = nil
+		primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var notice *gobrake.Notice
+			if strings.HasSuffix(req.URL.Path, "/notices") {
+				notice = new(gobrake.Notice)
+				Expect(json.NewDecoder(req.Body).Decode(notice)).To(Succeed())
+			}
+
+			mu.Lock()
+			secondaryPaths = append(secondaryPaths, req.URL.Path)
+			if notice != nil {
+				secondaryNotices = append(secondaryNotices, notice)
+			}
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       primary.URL,
+			DualWrite: &gobrake.NotifierOptions{
+				ProjectId:  2,
+				ProjectKey: "new-key",
+				Host:       secondary.URL,
+			},
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("sends notices to both backends independently", func() {
+		_, err := notifier.SendNotice(gobrake.NewNotice("hello", nil, 0))
+		Expect(err).To(MatchError(ContainSubstring("500 Internal Server Error")))
+
+		notifier.Flush()
+		Expect(secondaryPaths).To(Equal([]string{"/api/v3/projects/2/notices"}))
+		Expect(notifier.DualWriteNotifier().Stats().NoticesSent).To(Equal(uint32(1)))
+		Expect(notifier.Stats().NoticesFailed).To(Equal(uint32(1)))
+	})
+
+	It("mirrors notices after filters are applied", func() {
+		notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+			if notice.Errors[0].Message == "ignored" {
+				return nil
+			}
+			notice.Params["password"] = "[Filtered]"
+			return notice
+		})
+
+		notice := gobrake.NewNotice("hello", nil, 0)
+		notice.Params["password"] = "secret"
+		_, _ = notifier.SendNotice(notice)
+		_, _ = notifier.SendNotice(gobrake.NewNotice("ignored", nil, 0))
+
+		notifier.Flush()
+		Expect(secondaryNotices).To(HaveLen(1))
+		Expect(secondaryNotices[0].Params["password"]).To(Equal("[Filtered]"))
+	})
+
+	It("sends requests stats to both backends", func() {
+		start := time.Now()
+		err := notifier.NotifyRequest(&gobrake.RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(notifier.FlushContext(context.Background())).NotTo(HaveOccurred())
+		Expect(secondaryPaths).To(HaveLen(1))
+		Expect(strings.HasSuffix(secondaryPaths[0], "/projects/2/routes-stats")).To(BeTrue())
+	})
+})
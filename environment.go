@@ -0,0 +1,40 @@
+package gobrake
+
+import (
+	"os"
+	"path"
+)
+
+// environmentVars are checked in order to detect the environment when
+// NotifierOptions.Environment is not set.
+var environmentVars = []string{
+	"AIRBRAKE_ENVIRONMENT",
+	"APP_ENV",
+	"GO_ENV",
+	"ENVIRONMENT",
+	"ENV",
+	"RACK_ENV",
+}
+
+func detectEnvironment() string {
+	for _, name := range environmentVars {
+		if s := os.Getenv(name); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// environmentDisabled reports whether the environment matches one of
+// the DisabledEnvironments patterns.
+func (opt *NotifierOptions) environmentDisabled() bool {
+	if opt.Environment == "" {
+		return false
+	}
+	for _, pattern := range opt.DisabledEnvironments {
+		if ok, _ := path.Match(pattern, opt.Environment); ok {
+			return true
+		}
+	}
+	return false
+}
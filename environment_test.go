@@ -0,0 +1,48 @@
+package gobrake
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("environment", func() {
+	It("is disabled when matching a glob pattern", func() {
+		tests := []struct {
+			env      string
+			disabled bool
+		}{
+			{"development", true},
+			{"test-123", true},
+			{"production", false},
+			{"", false},
+		}
+
+		for _, test := range tests {
+			opt := &NotifierOptions{
+				Environment:          test.env,
+				DisabledEnvironments: []string{"development", "test-*"},
+			}
+			Expect(opt.environmentDisabled()).To(Equal(test.disabled), test.env)
+		}
+	})
+
+	It("is detected from environment variables", func() {
+		for _, k := range environmentVars {
+			orig, ok := os.LookupEnv(k)
+			if ok {
+				defer os.Setenv(k, orig)
+			} else {
+				defer os.Unsetenv(k)
+			}
+			os.Unsetenv(k)
+		}
+
+		os.Setenv("GO_ENV", "staging")
+		Expect(detectEnvironment()).To(Equal("staging"))
+
+		os.Setenv("APP_ENV", "production")
+		Expect(detectEnvironment()).To(Equal("production"))
+	})
+})
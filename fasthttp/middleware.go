@@ -0,0 +1,66 @@
+package fasthttp
+
+import (
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/valyala/fasthttp"
+)
+
+// NewMiddleware returns middleware that sends requests stats and reports
+// recovered panics to Airbrake. route returns the route template of the
+// request, e.g. /users/:id. The request path is used when route is nil.
+func NewMiddleware(
+	notifier *gobrake.Notifier, route func(ctx *fasthttp.RequestCtx) string,
+) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			defer func() {
+				if v := recover(); v != nil {
+					Notify(notifier, v, ctx)
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError),
+						fasthttp.StatusInternalServerError)
+				}
+
+				routeName := string(ctx.Path())
+				if route != nil {
+					routeName = route(ctx)
+				}
+				notifier.NotifyRequest(&gobrake.RequestInfo{
+					Method:     string(ctx.Method()),
+					Route:      routeName,
+					StatusCode: ctx.Response.StatusCode(),
+					Start:      start,
+					End:        time.Now(),
+				})
+			}()
+
+			next(ctx)
+		}
+	}
+}
+
+// Notify reports the error that happened while serving the request. The
+// notice is sent asynchronously and does not reference ctx which is
+// reused by fasthttp once the handler returns.
+func Notify(notifier *gobrake.Notifier, e interface{}, ctx *fasthttp.RequestCtx) {
+	notice := notifier.Notice(e, nil, 1)
+	notifier.SetNoticeRequest(notice, RequestDetails(ctx))
+	notifier.SendNoticeAsync(notice)
+}
+
+// RequestDetails returns the details of the request that can be attached
+// to notices.
+func RequestDetails(ctx *fasthttp.RequestCtx) *gobrake.RequestDetails {
+	return &gobrake.RequestDetails{
+		Method:     string(ctx.Method()),
+		URL:        ctx.URI().String(),
+		RemoteAddr: ctx.RemoteAddr().String(),
+		VisitHeaders: func(fn func(key, value string)) {
+			ctx.Request.Header.VisitAll(func(k, v []byte) {
+				fn(string(k), string(v))
+			})
+		},
+	}
+}
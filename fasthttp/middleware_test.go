@@ -0,0 +1,107 @@
+package fasthttp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestRequest(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Request-Id", "123")
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Init(&req, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}, nil)
+	return ctx
+}
+
+func newTestNotifier(t *testing.T) (*gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	return notifier, server
+}
+
+func TestMiddlewareReportsRouteAndStatus(t *testing.T) {
+	notifier, server := newTestNotifier(t)
+	route := func(ctx *fasthttp.RequestCtx) string {
+		return "/users/:id"
+	}
+	handler := NewMiddleware(notifier, route)(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+	})
+
+	handler(newTestRequest("POST", "/users/1"))
+	handler(newTestRequest("POST", "/users/2"))
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 {
+		t.Fatalf("got routes %+v, wanted 1", routes)
+	}
+	r := routes[0]
+	if r.Method != "POST" || r.Route != "/users/:id" || r.StatusCode != 202 || r.Count != 2 {
+		t.Errorf("got route %+v", r)
+	}
+}
+
+func TestMiddlewareUsesPathWithoutRoute(t *testing.T) {
+	notifier, server := newTestNotifier(t)
+	handler := NewMiddleware(notifier, nil)(func(ctx *fasthttp.RequestCtx) {})
+
+	handler(newTestRequest("GET", "/hello?a=1"))
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].Route != "/hello" || routes[0].StatusCode != 200 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
+
+func TestMiddlewareReportsPanics(t *testing.T) {
+	notifier, server := newTestNotifier(t)
+	handler := NewMiddleware(notifier, nil)(func(ctx *fasthttp.RequestCtx) {
+		panic("boom")
+	})
+
+	ctx := newTestRequest("GET", "http://example.com/boom")
+	handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Fatalf("got %d, wanted 500", ctx.Response.StatusCode())
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if notice.Errors[0].Message != "boom" {
+		t.Errorf("got %q, wanted boom", notice.Errors[0].Message)
+	}
+	if url, _ := notice.Context["url"].(string); !strings.HasSuffix(url, "/boom") {
+		t.Errorf("got url %q", url)
+	}
+	if notice.Context["userAddr"] != "10.0.0.1" {
+		t.Errorf("got userAddr %v", notice.Context["userAddr"])
+	}
+	if _, ok := notice.Env["Authorization"]; ok {
+		t.Error("Authorization header is reported")
+	}
+	if notice.Env["X-Request-Id"] != "123" {
+		t.Errorf("got env %v", notice.Env)
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].StatusCode != 500 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
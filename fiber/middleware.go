@@ -0,0 +1,49 @@
+//go:build go1.17
+// +build go1.17
+
+package fiber
+
+import (
+	"errors"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	gobrakefasthttp "github.com/airbrake/gobrake/fasthttp"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewMiddleware returns Fiber middleware that sends requests stats using
+// route templates from Fiber's router and reports recovered panics and
+// returned errors with 5xx status to Airbrake.
+func NewMiddleware(notifier *gobrake.Notifier) fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		start := time.Now()
+		defer func() {
+			if v := recover(); v != nil {
+				gobrakefasthttp.Notify(notifier, v, c.Context())
+				err = fiber.ErrInternalServerError
+			}
+
+			statusCode := c.Response().StatusCode()
+			if err != nil {
+				statusCode = fiber.StatusInternalServerError
+				var fiberErr *fiber.Error
+				if errors.As(err, &fiberErr) {
+					statusCode = fiberErr.Code
+				} else {
+					gobrakefasthttp.Notify(notifier, err, c.Context())
+				}
+			}
+
+			notifier.NotifyRequest(&gobrake.RequestInfo{
+				Method:     c.Method(),
+				Route:      c.Route().Path,
+				StatusCode: statusCode,
+				Start:      start,
+				End:        time.Now(),
+			})
+		}()
+
+		return c.Next()
+	}
+}
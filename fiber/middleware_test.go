@@ -0,0 +1,114 @@
+//go:build go1.17
+// +build go1.17
+
+package fiber
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestApp(t *testing.T) (*fiber.App, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+
+	app := fiber.New()
+	app.Use(NewMiddleware(notifier))
+	return app, notifier, server
+}
+
+func testRequest(t *testing.T, app *fiber.App, method, target string) int {
+	resp, err := app.Test(httptest.NewRequest(method, target, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMiddlewareReportsRouteAndStatus(t *testing.T) {
+	app, notifier, server := newTestApp(t)
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	for _, target := range []string{"/users/1", "/users/2", "/missing"} {
+		testRequest(t, app, "GET", target)
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	got := make(map[string]gobraketest.RouteStat)
+	for _, r := range routes {
+		got[r.Route] = r
+	}
+	if r := got["/users/:id"]; r.StatusCode != 204 || r.Count != 2 {
+		t.Errorf("got route %+v", r)
+	}
+	if r := got["/missing"]; r.StatusCode != 404 || r.Count != 1 {
+		t.Errorf("got route %+v", r)
+	}
+	if len(server.Notices()) != 0 {
+		t.Errorf("got %d notices, wanted 0", len(server.Notices()))
+	}
+}
+
+func TestMiddlewareReportsPanics(t *testing.T) {
+	app, notifier, server := newTestApp(t)
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+
+	if code := testRequest(t, app, "GET", "/boom"); code != 500 {
+		t.Fatalf("got %d, wanted 500", code)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notices[0].Errors[0].Message != "boom" {
+		t.Errorf("got %q, wanted boom", notices[0].Errors[0].Message)
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].Route != "/boom" || routes[0].StatusCode != 500 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
+
+func TestMiddlewareReportsReturnedErrors(t *testing.T) {
+	app, notifier, server := newTestApp(t)
+	app.Get("/orders", func(c *fiber.Ctx) error {
+		return errors.New("database is down")
+	})
+
+	if code := testRequest(t, app, "GET", "/orders"); code != 500 {
+		t.Fatalf("got %d, wanted 500", code)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notices[0].Errors[0].Message != "database is down" {
+		t.Errorf("got %q", notices[0].Errors[0].Message)
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].StatusCode != 500 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
@@ -3,6 +3,7 @@ package gobrake
 import (
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 )
@@ -10,6 +11,18 @@ import (
 func newNotifierFilter(notifier *Notifier) func(*Notice) *Notice {
 	opt := notifier.opt
 	return func(notice *Notice) *Notice {
+		if len(opt.DefaultContext) > 0 && notice.Context == nil {
+			notice.Context = make(map[string]interface{}, len(opt.DefaultContext))
+		}
+		// DefaultContext overrides library defaults, e.g. hostname, but
+		// not values set by the caller.
+		for k, v := range opt.DefaultContext {
+			if cur, ok := notice.Context[k]; !ok || isDefaultContextValue(k, cur) {
+				notice.Context[k] = v
+			}
+		}
+		notifier.mergeDefaultParams(notice)
+
 		if opt.Environment != "" {
 			notice.Context["environment"] = opt.Environment
 		}
@@ -20,6 +33,13 @@ func newNotifierFilter(notifier *Notifier) func(*Notice) *Notice {
 	}
 }
 
+// isDefaultContextValue reports whether the context value is the one
+// NewNotice sets by default.
+func isDefaultContextValue(key string, value interface{}) bool {
+	v, ok := getDefaultContext()[key]
+	return ok && reflect.DeepEqual(v, value)
+}
+
 func NewBlacklistKeysFilter(keys ...interface{}) func(*Notice) *Notice {
 	return func(notice *Notice) *Notice {
 		for _, key := range keys {
@@ -18,13 +18,17 @@ func NewMiddleware(engine *gin.Engine, notifier *gobrake.Notifier) func(c *gin.C
 		end := time.Now()
 
 		routeName := getRouteName(c, engine)
-		notifier.NotifyRequest(&gobrake.RequestInfo{
+		info := &gobrake.RequestInfo{
 			Method:     c.Request.Method,
 			Route:      routeName,
 			StatusCode: c.Writer.Status(),
 			Start:      start,
 			End:        end,
-		})
+		}
+		if notifier.ReportsClientIdentity() {
+			info.ClientIdentity = gobrake.ClientCertIdentity(c.Request)
+		}
+		notifier.NotifyRequest(info)
 	}
 }
 
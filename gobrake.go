@@ -5,12 +5,20 @@ import (
 	"os"
 )
 
-var logger *log.Logger
+// Logger is used to report internal diagnostics. It is satisfied by
+// *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var logger Logger
 
 func init() {
 	SetLogger(log.New(os.Stderr, "gobrake: ", log.LstdFlags))
 }
 
-func SetLogger(l *log.Logger) {
+// SetLogger sets the logger that is used by notifiers without
+// NotifierOptions.Logger and by package level functions.
+func SetLogger(l Logger) {
 	logger = l
 }
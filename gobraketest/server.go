@@ -0,0 +1,311 @@
+// Package gobraketest provides a fake Airbrake API that records notices,
+// route and query stats and check-ins, so applications can assert in unit
+// tests what was reported.
+package gobraketest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+)
+
+// Host is the Airbrake host used by notifiers created with
+// Server.Options. Requests to it never leave the process.
+const Host = "https://gobraketest.invalid"
+
+// RouteStat is a route stat received by the server.
+type RouteStat struct {
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	StatusCode int       `json:"statusCode"`
+	Time       time.Time `json:"time"`
+	Count      int       `json:"count"`
+	Sum        float64   `json:"sum"`
+}
+
+// GroupStat is the stat of a route breakdown group.
+type GroupStat struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+// RouteBreakdown is a route breakdown received by the server.
+type RouteBreakdown struct {
+	RouteStat
+	Groups map[string]GroupStat `json:"groups"`
+}
+
+// QueryStat is a query stat received by the server.
+type QueryStat struct {
+	Method string    `json:"method"`
+	Route  string    `json:"route"`
+	Query  string    `json:"query"`
+	Func   string    `json:"function"`
+	File   string    `json:"file"`
+	Line   int       `json:"line"`
+	Time   time.Time `json:"time"`
+	Count  int       `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+// CheckIn is a check-in received by the server.
+type CheckIn struct {
+	Name        string    `json:"name"`
+	Environment string    `json:"environment"`
+	Time        time.Time `json:"time"`
+}
+
+// Server is a fake Airbrake API. It can be used as http.RoundTripper of
+// the notifier HTTP client, which keeps requests in memory, or served
+// with httptest.NewServer as a http.Handler.
+type Server struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	notices    []*gobrake.Notice
+	routes     []RouteStat
+	breakdowns []RouteBreakdown
+	queries    []QueryStat
+	checkIns   []CheckIn
+	status     int
+}
+
+var _ http.Handler = (*Server)(nil)
+var _ http.RoundTripper = (*Server)(nil)
+
+// NewServer returns a server that accepts all requests.
+func NewServer() *Server {
+	s := &Server{}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Options returns notifier options that send notices, stats and
+// check-ins to the server in memory. Remote config is disabled.
+func (s *Server) Options() *gobrake.NotifierOptions {
+	return &gobrake.NotifierOptions{
+		ProjectId:           1,
+		ProjectKey:          "gobraketest",
+		Host:                Host,
+		HTTPClient:          &http.Client{Transport: s},
+		DisableRemoteConfig: true,
+	}
+}
+
+// NewNotifier returns a notifier created with Options.
+func (s *Server) NewNotifier() *gobrake.Notifier {
+	return gobrake.NewNotifierWithOptions(s.Options())
+}
+
+// SetStatusCode makes the server reject all following requests with the
+// status code, e.g. to test rate limiting or outages. Zero restores the
+// default behavior.
+func (s *Server) SetStatusCode(code int) {
+	s.mu.Lock()
+	s.status = code
+	s.mu.Unlock()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		w.Write([]byte(`{"message":"rejected by gobraketest"}`))
+		return
+	}
+
+	path := req.URL.Path
+	switch {
+	case req.Method == "POST" && strings.HasSuffix(path, "/notices"):
+		notice := new(gobrake.Notice)
+		if err := json.Unmarshal(b, notice); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.notices = append(s.notices, notice)
+
+		id := strconv.Itoa(len(s.notices))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{
+			"id":  id,
+			"url": Host + "/locate/" + id,
+		})
+	case req.Method == "PUT" && strings.HasSuffix(path, "/routes-stats"):
+		var body struct {
+			Routes []RouteStat `json:"routes"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.routes = append(s.routes, body.Routes...)
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == "PUT" && strings.HasSuffix(path, "/routes-breakdowns"):
+		var body struct {
+			Routes []RouteBreakdown `json:"routes"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.breakdowns = append(s.breakdowns, body.Routes...)
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == "PUT" && strings.HasSuffix(path, "/queries-stats"):
+		var body struct {
+			Queries []QueryStat `json:"queries"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.queries = append(s.queries, body.Queries...)
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == "PUT" && strings.Contains(path, "/check-ins/"):
+		var checkIn CheckIn
+		if err := json.Unmarshal(b, &checkIn); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.checkIns = append(s.checkIns, checkIn)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.cond.Broadcast()
+}
+
+// Notices returns notices received so far.
+func (s *Server) Notices() []*gobrake.Notice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*gobrake.Notice(nil), s.notices...)
+}
+
+// Routes returns route stats received so far. Stats are sent when the
+// notifier is flushed with FlushContext or closed.
+func (s *Server) Routes() []RouteStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RouteStat(nil), s.routes...)
+}
+
+// RouteBreakdowns returns route breakdowns received so far.
+func (s *Server) RouteBreakdowns() []RouteBreakdown {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RouteBreakdown(nil), s.breakdowns...)
+}
+
+// Queries returns query stats received so far. Like route stats, they
+// are sent when the notifier is flushed with FlushContext or closed.
+func (s *Server) Queries() []QueryStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]QueryStat(nil), s.queries...)
+}
+
+// CheckIns returns check-ins received so far.
+func (s *Server) CheckIns() []CheckIn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CheckIn(nil), s.checkIns...)
+}
+
+// Flush sends pending notices and collected stats of the notifier to the
+// server and fails the test when they can not be sent.
+func (s *Server) Flush(t testing.TB, notifier *gobrake.Notifier) {
+	t.Helper()
+	if err := notifier.FlushContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Reset forgets everything received so far.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.notices = nil
+	s.routes = nil
+	s.breakdowns = nil
+	s.queries = nil
+	s.checkIns = nil
+	s.mu.Unlock()
+}
+
+// WaitForNotices waits until at least n notices are received and returns
+// them. It returns the notices received so far and an error on timeout.
+func (s *Server) WaitForNotices(n int, timeout time.Duration) ([]*gobrake.Notice, error) {
+	err := s.wait(timeout, "notices", n, func() int { return len(s.notices) })
+	return s.Notices(), err
+}
+
+// WaitForRoutes is like WaitForNotices, but waits for route stats.
+func (s *Server) WaitForRoutes(n int, timeout time.Duration) ([]RouteStat, error) {
+	err := s.wait(timeout, "routes", n, func() int { return len(s.routes) })
+	return s.Routes(), err
+}
+
+// WaitForQueries is like WaitForNotices, but waits for query stats.
+func (s *Server) WaitForQueries(n int, timeout time.Duration) ([]QueryStat, error) {
+	err := s.wait(timeout, "queries", n, func() int { return len(s.queries) })
+	return s.Queries(), err
+}
+
+// WaitForCheckIns is like WaitForNotices, but waits for check-ins.
+func (s *Server) WaitForCheckIns(n int, timeout time.Duration) ([]CheckIn, error) {
+	err := s.wait(timeout, "check-ins", n, func() int { return len(s.checkIns) })
+	return s.CheckIns(), err
+}
+
+func (s *Server) wait(timeout time.Duration, what string, n int, count func() int) error {
+	// sync.Cond can not wait with a timeout, so waiters are woken up
+	// when the deadline passes.
+	timer := time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for count() < n {
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("gobraketest: got %d %s, wanted %d after %s",
+				count(), what, n, timeout)
+		}
+		s.cond.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package gobraketest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+)
+
+func TestServerRecordsInMemory(t *testing.T) {
+	server := NewServer()
+	notifier := server.NewNotifier()
+	defer notifier.Close()
+
+	id, err := notifier.SendNotice(notifier.Notice(errors.New("boom"), nil, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "1" {
+		t.Errorf("got id %q, wanted 1", id)
+	}
+
+	start := time.Now()
+	err = notifier.NotifyRequest(&gobrake.RequestInfo{
+		Method:     "GET",
+		Route:      "/hello",
+		StatusCode: 200,
+		Start:      start,
+		End:        start.Add(time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Flush(t, notifier)
+
+	notices := server.Notices()
+	if len(notices) != 1 || notices[0].Errors[0].Message != "boom" {
+		t.Fatalf("got notices %+v", notices)
+	}
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].Route != "/hello" || routes[0].Count != 1 {
+		t.Fatalf("got routes %+v", routes)
+	}
+}
+
+func TestServerServesHTTP(t *testing.T) {
+	server := NewServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+		ProjectId:           1,
+		ProjectKey:          "key",
+		Host:                ts.URL,
+		DisableRemoteConfig: true,
+	})
+	defer notifier.Close()
+
+	notifier.Notify(errors.New("boom"), nil)
+	if err := notifier.CheckIn("nightly-backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notices[0].Errors[0].Message != "boom" {
+		t.Errorf("got %q, wanted boom", notices[0].Errors[0].Message)
+	}
+
+	checkIns, err := server.WaitForCheckIns(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkIns[0].Name != "nightly-backup" {
+		t.Errorf("got %q, wanted nightly-backup", checkIns[0].Name)
+	}
+
+	resp, err := http.Get(ts.URL + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d, wanted 404", resp.StatusCode)
+	}
+}
+
+func TestServerWaitForNoticesTimeout(t *testing.T) {
+	server := NewServer()
+
+	start := time.Now()
+	notices, err := server.WaitForNotices(1, 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "got 0 notices, wanted 1") {
+		t.Fatalf("got %v, wanted timeout error", err)
+	}
+	if len(notices) != 0 {
+		t.Errorf("got %d notices, wanted 0", len(notices))
+	}
+	if took := time.Since(start); took < 20*time.Millisecond {
+		t.Errorf("returned after %s, wanted at least 20ms", took)
+	}
+}
+
+func TestServerSetStatusCode(t *testing.T) {
+	server := NewServer()
+	notifier := server.NewNotifier()
+	defer notifier.Close()
+
+	server.SetStatusCode(http.StatusServiceUnavailable)
+	_, err := notifier.SendNotice(notifier.Notice(errors.New("boom"), nil, 0))
+	if err == nil || !strings.Contains(err.Error(), "rejected by gobraketest") {
+		t.Fatalf("got %v, wanted rejection", err)
+	}
+	if len(server.Notices()) != 0 {
+		t.Fatal("rejected notice is recorded")
+	}
+
+	server.SetStatusCode(0)
+	_, err = notifier.SendNotice(notifier.Notice(errors.New("boom"), nil, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Notices()) != 1 {
+		t.Fatalf("got %d notices, wanted 1", len(server.Notices()))
+	}
+}
+
+func TestServerReset(t *testing.T) {
+	server := NewServer()
+	notifier := server.NewNotifier()
+	defer notifier.Close()
+
+	_, err := notifier.SendNotice(notifier.Notice(errors.New("boom"), nil, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.CheckIn("nightly-backup"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.WaitForCheckIns(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Reset()
+	if len(server.Notices()) != 0 || len(server.CheckIns()) != 0 {
+		t.Fatal("server is not reset")
+	}
+}
@@ -0,0 +1,118 @@
+//go:build go1.18
+// +build go1.18
+
+package gorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"gorm.io/gorm"
+)
+
+const startKey = "gobrake:start"
+
+// Plugin is a GORM plugin that attributes query time to the "sql" group
+// of the route metric carried by the statement context, e.g. set with
+// db.WithContext(ctx), and, when created with a notifier, reports queries
+// stats and failed queries.
+//
+//	db.Use(gobrakegorm.NewPlugin(notifier))
+type Plugin struct {
+	notifier *gobrake.Notifier
+}
+
+// NewPlugin returns the plugin. Queries stats are reported and failed
+// queries are notified when notifier is not nil. gorm.ErrRecordNotFound,
+// canceled queries and driver.ErrBadConn are not reported.
+func NewPlugin(notifier *gobrake.Notifier) *Plugin {
+	return &Plugin{
+		notifier: notifier,
+	}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "gobrake"
+}
+
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// Initialize implements gorm.Plugin.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name          string
+		before, after registerer
+	}{
+		{"create", cb.Create().Before("gorm:create"), cb.Create().After("gorm:create")},
+		{"query", cb.Query().Before("gorm:query"), cb.Query().After("gorm:query")},
+		{"update", cb.Update().Before("gorm:update"), cb.Update().After("gorm:update")},
+		{"delete", cb.Delete().Before("gorm:delete"), cb.Delete().After("gorm:delete")},
+		{"row", cb.Row().Before("gorm:row"), cb.Row().After("gorm:row")},
+		{"raw", cb.Raw().Before("gorm:raw"), cb.Raw().After("gorm:raw")},
+	}
+	for _, h := range hooks {
+		err := h.before.Register("gobrake:before_"+h.name, before)
+		if err != nil {
+			return err
+		}
+		err = h.after.Register("gobrake:after_"+h.name, p.after)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *Plugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(startKey)
+	if !ok {
+		return
+	}
+	start, ok := v.(time.Time)
+	if !ok || db.Statement.Context == nil {
+		return
+	}
+	end := time.Now()
+	metric := gobrake.ContextRouteMetric(db.Statement.Context)
+	metric.AddGroup("sql", end.Sub(start))
+
+	if p.notifier == nil {
+		return
+	}
+
+	query := db.Statement.SQL.String()
+	q := &gobrake.QueryInfo{
+		Query: query,
+		Start: start,
+		End:   end,
+	}
+	if metric != nil {
+		q.Method, q.Route = metric.Method, metric.Route
+	}
+	p.notifier.NotifyQuery(q)
+
+	if db.Error != nil && reportable(db.Error) {
+		notice := p.notifier.Notice(db.Error, nil, 1)
+		notice.Context["component"] = "gorm"
+		notice.Params["query"] = query
+		p.notifier.SendNoticeAsync(notice)
+	}
+}
+
+func reportable(err error) bool {
+	return !errors.Is(err, gorm.ErrRecordNotFound) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, driver.ErrBadConn)
+}
@@ -0,0 +1,152 @@
+//go:build go1.18
+// +build go1.18
+
+package gorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func newTestDB(t *testing.T) (*gorm.DB, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+
+	// Dry run builds statements and runs callbacks without a database.
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewPlugin(notifier)); err != nil {
+		t.Fatal(err)
+	}
+	return db, notifier, server
+}
+
+func TestPluginRecordsQueries(t *testing.T) {
+	db, notifier, server := newTestDB(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/users")
+
+	var users []user
+	db.WithContext(ctx).Where("name = ?", "alice").Find(&users)
+	db.WithContext(ctx).Create(&user{Name: "bob"})
+
+	if _, ok := metric.Groups()["sql"]; !ok {
+		t.Error("sql group is not recorded")
+	}
+
+	server.Flush(t, notifier)
+	queries := server.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("got queries %+v, wanted 2", queries)
+	}
+	var selects, inserts int
+	for _, q := range queries {
+		if q.Method != "GET" || q.Route != "/users" || q.Count != 1 {
+			t.Errorf("got query %+v", q)
+		}
+		switch {
+		case strings.HasPrefix(q.Query, "SELECT"):
+			selects++
+		case strings.HasPrefix(q.Query, "INSERT"):
+			inserts++
+		}
+	}
+	if selects != 1 || inserts != 1 {
+		t.Errorf("got queries %+v", queries)
+	}
+	if len(server.Notices()) != 0 {
+		t.Errorf("got %d notices, wanted 0", len(server.Notices()))
+	}
+}
+
+func TestPluginReportsErrors(t *testing.T) {
+	db, _, server := newTestDB(t)
+	wanted := errors.New("connection reset")
+	err := db.Callback().Query().After("gorm:query").Before("gobrake:after_query").
+		Register("test:fail", func(db *gorm.DB) {
+			db.AddError(wanted)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []user
+	if err := db.Find(&users).Error; !errors.Is(err, wanted) {
+		t.Fatalf("got %v, wanted %v", err, wanted)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if notice.Errors[0].Message != "connection reset" {
+		t.Errorf("got %q", notice.Errors[0].Message)
+	}
+	if query, _ := notice.Params["query"].(string); !strings.HasPrefix(query, "SELECT") {
+		t.Errorf("got query %q", query)
+	}
+}
+
+func TestPluginIgnoresRecordNotFound(t *testing.T) {
+	db, notifier, server := newTestDB(t)
+	err := db.Callback().Query().After("gorm:query").Before("gobrake:after_query").
+		Register("test:not_found", func(db *gorm.DB) {
+			db.AddError(gorm.ErrRecordNotFound)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+	db.First(&u)
+
+	server.Flush(t, notifier)
+	if queries := server.Queries(); len(queries) != 1 {
+		t.Errorf("got queries %+v, wanted 1", queries)
+	}
+	if len(server.Notices()) != 0 {
+		t.Error("gorm.ErrRecordNotFound is reported")
+	}
+}
+
+func TestPluginIgnoresCanceledQueries(t *testing.T) {
+	for _, wanted := range []error{context.Canceled, context.DeadlineExceeded, driver.ErrBadConn} {
+		db, notifier, server := newTestDB(t)
+		err := db.Callback().Query().After("gorm:query").Before("gobrake:after_query").
+			Register("test:fail", func(db *gorm.DB) {
+				db.AddError(fmt.Errorf("query failed: %w", wanted))
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var users []user
+		db.Find(&users)
+
+		server.Flush(t, notifier)
+		if queries := server.Queries(); len(queries) != 1 {
+			t.Errorf("got queries %+v, wanted 1", queries)
+		}
+		if len(server.Notices()) != 0 {
+			t.Errorf("%v is reported", wanted)
+		}
+	}
+}
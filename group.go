@@ -0,0 +1,118 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Group is like errgroup.Group: it runs named tasks in goroutines and
+// returns the first error. The first failing task is reported to Airbrake
+// with the task name. Panics are recovered and every one of them is
+// reported. Task durations are added to the "task" breakdown group of the
+// route metric carried by the context.
+type Group struct {
+	notifier *Notifier
+	parent   context.Context
+	ctx      context.Context
+	cancel   func()
+	metric   *RouteMetric
+
+	wg  sync.WaitGroup
+	sem chan struct{}
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns new group and derived context that is canceled when
+// a task fails or Wait returns.
+func (n *Notifier) NewGroup(ctx context.Context) (*Group, context.Context) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		notifier: n,
+		parent:   parent,
+		ctx:      ctx,
+		cancel:   cancel,
+		metric:   ContextRouteMetric(ctx),
+	}, ctx
+}
+
+// SetLimit limits the number of tasks running at the same time, so the
+// group can be used as a worker pool. It must be called before Go.
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs the named task in a new goroutine. It blocks when the limit
+// set with SetLimit is reached.
+func (g *Group) Go(name string, fn func(context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			if g.sem != nil {
+				<-g.sem
+			}
+			g.wg.Done()
+		}()
+
+		panicked, err := g.run(name, fn)
+		if err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel()
+
+				if !panicked {
+					g.send(name, g.notifier.Notice(err, nil, 1))
+				}
+			})
+		}
+	}()
+}
+
+// groupMetric is the breakdown group task durations are added to. Task
+// names are not used, so they can not grow the number of groups.
+const groupMetric = "task"
+
+func (g *Group) run(name string, fn func(context.Context) error) (panicked bool, err error) {
+	start := time.Now()
+	defer func() {
+		g.metric.AddGroup(groupMetric, time.Since(start))
+
+		if v := recover(); v != nil {
+			// The notice is created here, so the backtrace starts at the
+			// panic.
+			notice := g.notifier.Notice(v, nil, 0)
+			notice.Context["severity"] = "critical"
+			g.send(name, notice)
+
+			err = fmt.Errorf("gobrake: task %q panicked: %v", name, v)
+			panicked = true
+		}
+	}()
+	return false, fn(g.ctx)
+}
+
+func (g *Group) send(name string, notice *Notice) {
+	notice.Context["task"] = name
+	notice.Params["task"] = name
+	// The group context may be already canceled.
+	g.notifier.SendNoticeAsyncContext(g.parent, notice)
+}
+
+// Wait waits for all tasks to finish and returns the first error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
@@ -0,0 +1,72 @@
+package gobrake
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// normalizeHost returns host as scheme://host[:port][/path] without
+// trailing slash so API URLs can be built by appending paths. Scheme
+// defaults to https and plain http is accepted only for loopback hosts
+// unless allowInsecure is set.
+func normalizeHost(host string, allowInsecure bool) (string, error) {
+	s := strings.TrimSpace(host)
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("gobrake: invalid host=%q: %s", host, err)
+	}
+	if u.Host == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("gobrake: invalid host=%q", host)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowInsecure && !isLoopbackHost(u.Hostname()) {
+			return "", fmt.Errorf(
+				"gobrake: plain http host=%q is not allowed (set AllowInsecureHost)", host)
+		}
+	default:
+		return "", fmt.Errorf("gobrake: unsupported host=%q scheme=%q", host, u.Scheme)
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// normalizeHosts normalizes Host and RemoteConfigHost in place. Empty
+// hosts are left for init to fill in with defaults.
+func (opt *NotifierOptions) normalizeHosts() error {
+	if opt.Host != "" {
+		host, err := normalizeHost(opt.Host, opt.AllowInsecureHost)
+		if err != nil {
+			return err
+		}
+		opt.Host = host
+	}
+	if opt.RemoteConfigHost != "" {
+		host, err := normalizeHost(opt.RemoteConfigHost, opt.AllowInsecureHost)
+		if err != nil {
+			return err
+		}
+		opt.RemoteConfigHost = host
+	}
+	return nil
+}
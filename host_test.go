@@ -0,0 +1,58 @@
+package gobrake
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("normalizeHost", func() {
+	It("normalizes scheme, case and trailing slash", func() {
+		tests := []struct {
+			host     string
+			expected string
+		}{
+			{"api.airbrake.io", "https://api.airbrake.io"},
+			{"https://api.airbrake.io/", "https://api.airbrake.io"},
+			{" HTTPS://API.Airbrake.io ", "https://api.airbrake.io"},
+			{"errbit.internal:8443/airbrake//", "https://errbit.internal:8443/airbrake"},
+			{"http://localhost:8080/", "http://localhost:8080"},
+			{"http://127.0.0.1:1234", "http://127.0.0.1:1234"},
+			{"http://[::1]:1234", "http://[::1]:1234"},
+		}
+		for _, test := range tests {
+			host, err := normalizeHost(test.host, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(host).To(Equal(test.expected))
+		}
+	})
+
+	It("rejects plain http unless it is allowed", func() {
+		_, err := normalizeHost("http://errbit.internal", false)
+		Expect(err).To(MatchError(
+			`gobrake: plain http host="http://errbit.internal" is not allowed (set AllowInsecureHost)`))
+
+		host, err := normalizeHost("http://errbit.internal/", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(host).To(Equal("http://errbit.internal"))
+	})
+
+	It("rejects invalid hosts", func() {
+		for _, host := range []string{"ftp://airbrake.io", "https://", "https://airbrake.io?x=1"} {
+			_, err := normalizeHost(host, true)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("makes the notifier return the error", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://errbit.internal",
+		})
+		defer notifier.Close()
+
+		_, err := notifier.SendNotice(NewNotice("hello", nil, 0))
+		Expect(err).To(MatchError(ContainSubstring("plain http")))
+		Expect(notifier.apmEnabled()).To(BeFalse())
+	})
+})
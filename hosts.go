@@ -0,0 +1,87 @@
+package gobrake
+
+import (
+	"sync"
+	"time"
+)
+
+// maxOutboundHosts limits the number of hosts tracked by hostStats.
+// Requests to other hosts are counted under otherHosts.
+const maxOutboundHosts = 1000
+
+const otherHosts = "other"
+
+// HostStats contains latencies of outbound requests to an external host
+// collected since the notifier was created.
+type HostStats struct {
+	Count int `json:"count"`
+	// Requests that failed with a transport error or 5xx response.
+	Errors int           `json:"errors"`
+	Sum    time.Duration `json:"sum"`
+	Max    time.Duration `json:"max"`
+}
+
+// hostStats aggregates outbound request latencies by host separately
+// from requests stats, so calls to other services are not reported as
+// routes of this one. Methods are safe to call on nil stats.
+type hostStats struct {
+	mu sync.Mutex
+	m  map[string]*HostStats
+}
+
+func (s *hostStats) Add(host string, dur time.Duration, failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m == nil {
+		s.m = make(map[string]*HostStats)
+	}
+	stat, ok := s.m[host]
+	if !ok {
+		if len(s.m) >= maxOutboundHosts {
+			host = otherHosts
+		}
+		stat, ok = s.m[host]
+		if !ok {
+			stat = new(HostStats)
+			s.m[host] = stat
+		}
+	}
+	stat.Count++
+	if failed {
+		stat.Errors++
+	}
+	stat.Sum += dur
+	if dur > stat.Max {
+		stat.Max = dur
+	}
+}
+
+// Snapshot returns a copy of the stats or nil if there are none.
+func (s *hostStats) Snapshot() map[string]HostStats {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.m) == 0 {
+		return nil
+	}
+	m := make(map[string]HostStats, len(s.m))
+	for k, v := range s.m {
+		m[k] = *v
+	}
+	return m
+}
+
+// HostStats returns latencies of outbound requests by host reported by
+// RoundTripper.WithHostStats.
+func (n *Notifier) HostStats() map[string]HostStats {
+	return n.hosts.Snapshot()
+}
@@ -0,0 +1,109 @@
+package gobrake_test
+
+import (
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP client options", func() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"123"}`))
+	})
+
+	send := func(notifier *gobrake.Notifier) error {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		_, err := notifier.SendNotice(notice)
+		return err
+	}
+
+	It("trusts CA certificates from CACertFile", func() {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		caFile := filepath.Join(dir, "ca.pem")
+		b := pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.Certificate().Raw,
+		})
+		Expect(ioutil.WriteFile(caFile, b, 0600)).NotTo(HaveOccurred())
+
+		untrusted := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:      1,
+			ProjectKey:     "key",
+			Host:           server.URL,
+			RequestTimeout: time.Second,
+		})
+		defer untrusted.Close()
+		Expect(send(untrusted)).To(HaveOccurred())
+
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+			CACertFile: caFile,
+		})
+		defer notifier.Close()
+		Expect(send(notifier)).NotTo(HaveOccurred())
+	})
+
+	It("sends requests through ProxyURL", func() {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			proxied = req.URL.String()
+			handler(w, req)
+		}))
+		defer proxy.Close()
+
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:         1,
+			ProjectKey:        "key",
+			Host:              "http://airbrake.example.com",
+			AllowInsecureHost: true,
+			ProxyURL:          proxy.URL,
+		})
+		defer notifier.Close()
+
+		Expect(send(notifier)).NotTo(HaveOccurred())
+		Expect(proxied).To(Equal("http://airbrake.example.com/api/v3/projects/1/notices"))
+	})
+	It("does not send notices with invalid ProxyURL or CACertFile", func() {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			handler(w, req)
+		}))
+		defer server.Close()
+
+		for _, opt := range []*gobrake.NotifierOptions{
+			{ProxyURL: "://proxy"},
+			{ProxyURL: "proxy.internal:3128"},
+			{CACertFile: "/nonexistent/ca.pem"},
+		} {
+			opt.ProjectId = 1
+			opt.ProjectKey = "key"
+			opt.Host = server.URL
+			opt.DisableRemoteConfig = true
+			notifier := gobrake.NewNotifierWithOptions(opt)
+
+			Expect(send(notifier)).To(MatchError(HavePrefix("gobrake: ")))
+			Expect(notifier.Close()).NotTo(HaveOccurred())
+		}
+		Expect(requests).To(Equal(0))
+	})
+})
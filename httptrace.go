@@ -0,0 +1,114 @@
+package gobrake
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sendTrace records connection timings of a request so failed sends can
+// be diagnosed.
+type sendTrace struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+func withSendTrace(req *http.Request) (*http.Request, *sendTrace) {
+	t := &sendTrace{
+		start: time.Now(),
+	}
+	set := func(tm *time.Time) {
+		t.mu.Lock()
+		*tm = time.Now()
+		t.mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone:       func(network, addr string, err error) { set(&t.connectDone) },
+		TLSHandshakeStart: func() { set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	return req.WithContext(ctx), t
+}
+
+func (t *sendTrace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var parts []string
+	stage := func(name string, start, end time.Time) {
+		switch {
+		case start.IsZero():
+		case end.IsZero():
+			parts = append(parts, name+"=unfinished")
+		default:
+			parts = append(parts, name+"="+end.Sub(start).String())
+		}
+	}
+
+	stage("dns", t.dnsStart, t.dnsDone)
+	stage("connect", t.connectStart, t.connectDone)
+	stage("tls", t.tlsStart, t.tlsDone)
+	if t.gotConn.IsZero() {
+		parts = append(parts, "conn=none")
+	} else if t.reused {
+		parts = append(parts, "conn=reused")
+	}
+	if t.firstByte.IsZero() {
+		parts = append(parts, "elapsed="+time.Since(t.start).String())
+	} else {
+		stage("ttfb", t.start, t.firstByte)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// sendError is returned when a request fails before a response is
+// received. It includes connection timings of the failed attempt.
+type sendError struct {
+	err   error
+	trace *sendTrace
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error() + " (" + e.trace.String() + ")"
+}
+
+func (e *sendError) Cause() error {
+	return e.err
+}
+
+func (e *sendError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,44 @@
+package gobrake_test
+
+import (
+	"net"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("send failure diagnostics", func() {
+	var notifier *gobrake.Notifier
+	var logger *bufferLogger
+
+	BeforeEach(func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := ln.Addr().String()
+		ln.Close()
+
+		logger = new(bufferLogger)
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://" + addr,
+			Logger:     logger,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("includes connection timings", func() {
+		notice := notifier.Notice("hello", nil, 0)
+		_, err := notifier.SendNotice(notice)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(MatchRegexp(`connection refused \(connect=\S+ conn=none elapsed=\S+\)$`))
+
+		Expect(logger.lines).To(HaveLen(1))
+		Expect(logger.lines[0]).To(ContainSubstring("connect="))
+	})
+})
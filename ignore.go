@@ -0,0 +1,99 @@
+package gobrake
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ignoredErrors matches values that are never reported: sentinel errors,
+// error types set as reflect.Type, regexps and substrings of messages.
+type ignoredErrors struct {
+	errors   []error
+	types    []reflect.Type
+	patterns []*regexp.Regexp
+	strings  []string
+}
+
+func newIgnoredErrors(entries []interface{}) *ignoredErrors {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	m := &ignoredErrors{}
+	for _, entry := range entries {
+		switch entry := entry.(type) {
+		case reflect.Type:
+			m.types = append(m.types, entry)
+		case *regexp.Regexp:
+			m.patterns = append(m.patterns, entry)
+		case string:
+			m.strings = append(m.strings, entry)
+		case error:
+			m.errors = append(m.errors, entry)
+		default:
+			panic(fmt.Errorf("unsupported ignored error type: %T", entry))
+		}
+	}
+	return m
+}
+
+// Match reports whether v or any error it wraps is ignored. It is safe to
+// call on nil value.
+func (m *ignoredErrors) Match(v interface{}) bool {
+	if m == nil || v == nil {
+		return false
+	}
+	if notice, ok := v.(*Notice); ok {
+		return m.Match(notice.cause)
+	}
+
+	err, ok := v.(error)
+	if !ok {
+		return m.matchMessage(fmt.Sprint(v))
+	}
+	return findError(err, m.matchError)
+}
+
+func (m *ignoredErrors) matchError(err error) bool {
+	for _, target := range m.errors {
+		if isError(err, target) {
+			return true
+		}
+	}
+
+	typ := reflect.TypeOf(err)
+	for _, t := range m.types {
+		if typ == t || (t.Kind() == reflect.Interface && typ.Implements(t)) {
+			return true
+		}
+	}
+
+	return m.matchMessage(err.Error())
+}
+
+func (m *ignoredErrors) matchMessage(msg string) bool {
+	for _, re := range m.patterns {
+		if re.MatchString(msg) {
+			return true
+		}
+	}
+	for _, s := range m.strings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isError is like errors.Is for a single error in the chain.
+func isError(err, target error) bool {
+	if reflect.TypeOf(err).Comparable() && err == target {
+		return true
+	}
+	if e, ok := err.(interface{ Is(error) bool }); ok {
+		return e.Is(target)
+	}
+	return false
+}
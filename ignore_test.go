@@ -0,0 +1,68 @@
+package gobrake_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"regexp"
+
+	"github.com/airbrake/gobrake"
+	pkgerrors "github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IgnoredErrors", func() {
+	var notifier *gobrake.Notifier
+	var filtered []*gobrake.Notice
+
+	BeforeEach(func() {
+		filtered = nil
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost:1",
+			IgnoredErrors: []interface{}{
+				context.Canceled,
+				reflect.TypeOf(&net.OpError{}),
+				reflect.TypeOf((*net.Error)(nil)).Elem(),
+				regexp.MustCompile(`^read: connection reset`),
+				"broken pipe",
+			},
+		})
+		notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+			filtered = append(filtered, notice)
+			return nil
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("drops matching errors before filters", func() {
+		ignored := []interface{}{
+			pkgerrors.Wrap(context.Canceled, "query"),
+			&net.OpError{Op: "dial", Err: errors.New("refused")},
+			&net.DNSError{Err: "no such host"},
+			errors.New("read: connection reset by peer"),
+			errors.New("write: broken pipe"),
+			"broken pipe",
+		}
+		for _, e := range ignored {
+			notifier.Notify(e, nil)
+			_, err := notifier.SendNotice(gobrake.NewNotice(e, nil, 0))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		notifier.Flush()
+		Expect(filtered).To(BeEmpty())
+	})
+
+	It("keeps other errors", func() {
+		_, err := notifier.SendNotice(gobrake.NewNotice(errors.New("connection reset"), nil, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filtered).To(HaveLen(1))
+	})
+})
@@ -1,8 +1,14 @@
 package gobrake
 
 import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -39,3 +45,128 @@ var _ = Describe("findGitDir", func() {
 		}
 	})
 })
+
+var _ = Describe("Notice.clone", func() {
+	It("copies nested maps and slices", func() {
+		notice := NewNotice("hello", nil, 0)
+		notice.Params["user"] = map[string]interface{}{"password": "secret"}
+		notice.Context["tags"] = []interface{}{"a"}
+
+		cp := notice.clone()
+		notice.Params["user"].(map[string]interface{})["password"] = "[Filtered]"
+		notice.Context["tags"].([]interface{})[0] = "b"
+
+		Expect(cp.Params["user"]).To(Equal(map[string]interface{}{"password": "secret"}))
+		Expect(cp.Context["tags"]).To(Equal([]interface{}{"a"}))
+	})
+})
+
+var _ = Describe("Notifier.NotifyEvery", func() {
+	It("reports error again after interval", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                "http://localhost:1",
+			DisableRemoteConfig: true,
+		})
+		defer notifier.Close()
+		clock := newStatsClock()
+		notifier.opt.clock = clock
+		notifier.AddFilter(func(*Notice) *Notice {
+			return nil
+		})
+
+		err := errors.New("cache is unreachable")
+		Expect(notifier.NotifyEvery("cache", time.Minute, err)).To(BeTrue())
+		Expect(notifier.NotifyEvery("cache", time.Minute, err)).To(BeFalse())
+
+		clock.mu.Lock()
+		clock.offset = time.Minute
+		clock.mu.Unlock()
+		Expect(notifier.NotifyEvery("cache", time.Minute, err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("isInAppPackage", func() {
+	It("treats the notifier, its subpackages and logging libraries as library code", func() {
+		for _, pkg := range []string{
+			"runtime",
+			"github.com/airbrake/gobrake",
+			"github.com/airbrake/gobrake/slog",
+			"github.com/airbrake/gobrake/logrus",
+			"log/slog",
+			"github.com/sirupsen/logrus",
+		} {
+			Expect(isInAppPackage(pkg)).To(BeFalse(), pkg)
+		}
+		for _, pkg := range []string{
+			"main",
+			"github.com/airbrake/gobrake_test",
+			"github.com/airbrake/gobrake/slog_test",
+			"github.com/airbrake/gobrake/internal/testpkg1",
+			"github.com/airbrake/gobrakeish",
+		} {
+			Expect(isInAppPackage(pkg)).To(BeTrue(), pkg)
+		}
+	})
+})
+
+var _ = Describe("remoteConfig.apply", func() {
+	It("normalizes endpoints and ignores insecure and invalid ones", func() {
+		var buf bytes.Buffer
+		opt := &NotifierOptions{
+			Host:   "https://api.airbrake.io",
+			Logger: log.New(&buf, "", 0),
+		}
+		c := &remoteConfig{opt: opt}
+
+		errorHost := "https://Errors.Example.com/"
+		apmHost := "http://apm.example.com"
+		c.apply(&remoteConfigJSON{Settings: []remoteSetting{
+			{Name: "errors", Enabled: true, Endpoint: &errorHost},
+			{Name: "apm", Enabled: true, Endpoint: &apmHost},
+		}})
+		Expect(c.ErrorHost(opt)).To(Equal("https://errors.example.com"))
+		Expect(c.APMHost(opt)).To(Equal("https://api.airbrake.io"))
+		Expect(buf.String()).To(ContainSubstring(`remoteConfig.apply setting="apm" failed: ` +
+			`gobrake: plain http host="http://apm.example.com" is not allowed`))
+
+		apmHost = "https://apm.example.com?key=1"
+		c.apply(&remoteConfigJSON{Settings: []remoteSetting{
+			{Name: "apm", Enabled: true, Endpoint: &apmHost},
+		}})
+		Expect(c.APMHost(opt)).To(Equal("https://api.airbrake.io"))
+	})
+})
+
+var _ = Describe("VerifySignature", func() {
+	key := []byte("secret")
+	body := []byte("{}")
+
+	sign := func(at time.Time) *http.Request {
+		req, err := http.NewRequest("PUT", "https://relay/api/v5/projects/1/routes-stats", nil)
+		Expect(err).NotTo(HaveOccurred())
+		ts := strconv.FormatInt(at.Unix(), 10)
+		req.Header.Set(signatureTimestampHeader, ts)
+		req.Header.Set(signatureHeader, "sha256="+signPayload(key, ts, req, body))
+		return req
+	}
+
+	It("accepts fresh signatures", func() {
+		Expect(VerifySignature(key, sign(time.Now()), body, time.Minute)).To(BeTrue())
+	})
+
+	It("rejects old signatures", func() {
+		Expect(VerifySignature(key, sign(time.Now().Add(-time.Hour)), body, time.Minute)).To(BeFalse())
+	})
+
+	It("rejects signatures from the future", func() {
+		Expect(VerifySignature(key, sign(time.Now().Add(time.Hour)), body, time.Minute)).To(BeFalse())
+	})
+
+	It("rejects signatures for another method", func() {
+		req := sign(time.Now())
+		req.Method = "POST"
+		Expect(VerifySignature(key, req, body, time.Minute)).To(BeFalse())
+	})
+})
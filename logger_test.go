@@ -0,0 +1,57 @@
+package gobrake_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type bufferLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *bufferLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+	l.mu.Unlock()
+}
+
+var _ = Describe("Logger option", func() {
+	var notifier *gobrake.Notifier
+	var logger *bufferLogger
+
+	BeforeEach(func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		logger = new(bufferLogger)
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+			Logger:     logger,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("reports send failures to the logger", func() {
+		notice := notifier.Notice("hello", nil, 3)
+		_, err := notifier.SendNotice(notice)
+		Expect(err).To(HaveOccurred())
+
+		Expect(logger.lines).To(HaveLen(1))
+		Expect(logger.lines[0]).To(ContainSubstring("SendNotice failed"))
+	})
+})
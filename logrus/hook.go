@@ -0,0 +1,95 @@
+package logrus
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/airbrake/gobrake"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestKey is the entry field that may hold *http.Request to be
+// reported with the notice.
+const RequestKey = "request"
+
+type Hook struct {
+	notifier *gobrake.Notifier
+	levels   []logrus.Level
+}
+
+var _ logrus.Hook = (*Hook)(nil)
+
+// NewHook returns logrus hook that sends Error, Fatal and Panic entries
+// to Airbrake using the notifier. Entry fields are reported as notice params.
+func NewHook(notifier *gobrake.Notifier) *Hook {
+	return &Hook{
+		notifier: notifier,
+		levels: []logrus.Level{
+			logrus.PanicLevel,
+			logrus.FatalLevel,
+			logrus.ErrorLevel,
+		},
+	}
+}
+
+// SetLevels overrides the levels for which entries are reported.
+func (h *Hook) SetLevels(levels ...logrus.Level) {
+	h.levels = levels
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	var e interface{} = entry.Message
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		e = err
+	}
+	req, _ := entry.Data[RequestKey].(*http.Request)
+
+	notice := h.notifier.Notice(e, req, 0)
+
+	for k, v := range entry.Data {
+		if k == logrus.ErrorKey || k == RequestKey {
+			continue
+		}
+		notice.Params[k] = v
+	}
+	if e != entry.Message && entry.Message != "" {
+		notice.Params["message"] = entry.Message
+	}
+	notice.Context["severity"] = severity(entry.Level)
+
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if entry.Level <= logrus.FatalLevel {
+		// The process is about to exit or panic so the notice is sent
+		// synchronously.
+		_, err := h.notifier.SendNoticeContext(ctx, notice)
+		return err
+	}
+
+	// The entry context is usually canceled soon after logging, e.g. when
+	// the HTTP request is done, so only its values are kept.
+	h.notifier.SendNoticeAsyncContext(gobrake.WithoutCancel(ctx), notice)
+	return nil
+}
+
+func severity(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return "critical"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
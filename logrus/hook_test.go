@@ -0,0 +1,102 @@
+package logrus
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger(t *testing.T) (*logrus.Logger, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.ExitFunc = func(int) {}
+	logger.AddHook(NewHook(notifier))
+	return logger, notifier, server
+}
+
+func TestHookMapsLevels(t *testing.T) {
+	logger, _, server := newTestLogger(t)
+
+	logger.Warn("slow")
+	logger.Error("failed")
+	logger.Fatal("exiting")
+	func() {
+		defer func() { recover() }()
+		logger.Panic("panicking")
+	}()
+
+	notices, err := server.WaitForNotices(3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	severities := make(map[string]interface{})
+	for _, notice := range notices {
+		severities[notice.Errors[0].Message] = notice.Context["severity"]
+	}
+	wanted := map[string]interface{}{
+		"failed":    "error",
+		"exiting":   "critical",
+		"panicking": "critical",
+	}
+	if len(severities) != len(wanted) {
+		t.Fatalf("got severities %v, wanted %v", severities, wanted)
+	}
+	for msg, severity := range wanted {
+		if severities[msg] != severity {
+			t.Fatalf("got severities %v, wanted %v", severities, wanted)
+		}
+	}
+}
+
+func TestHookReportsFieldsAsParams(t *testing.T) {
+	logger, _, server := newTestLogger(t)
+
+	logger.WithError(errors.New("boom")).WithField("user", 42).Error("request failed")
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if got := notice.Errors[0].Message; got != "boom" {
+		t.Fatalf("got message %q, wanted boom", got)
+	}
+	if got := notice.Params["message"]; got != "request failed" {
+		t.Fatalf("got message param %v", got)
+	}
+	if got := notice.Params["user"]; got != float64(42) {
+		t.Fatalf("got user param %v", got)
+	}
+	if _, ok := notice.Params[logrus.ErrorKey]; ok {
+		t.Fatalf("error field is reported as a param: %v", notice.Params)
+	}
+}
+
+func TestHookHonorsFilters(t *testing.T) {
+	logger, notifier, server := newTestLogger(t)
+	notifier.AddFilter(func(notice *gobrake.Notice) *gobrake.Notice {
+		if notice.Params["secret"] != nil {
+			return nil
+		}
+		return notice
+	})
+
+	logger.WithField("secret", true).Error("filtered")
+	logger.WithField("secret", true).Fatal("filtered")
+	logger.Error("reported")
+
+	server.Flush(t, notifier)
+	notices := server.Notices()
+	if len(notices) != 1 || notices[0].Errors[0].Message != "reported" {
+		t.Fatalf("got %d notices, wanted only the unfiltered one", len(notices))
+	}
+}
@@ -0,0 +1,256 @@
+package gobrake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cloudMetadataTimeout = time.Second
+
+// Metadata endpoints and files are variables so tests can replace them.
+var (
+	ec2MetadataURL    = "http://169.254.169.254"
+	gceMetadataURL    = "http://metadata.google.internal"
+	cgroupFiles       = []string{"/proc/self/cgroup", "/proc/self/mountinfo"}
+	containerIDRegexp = regexp.MustCompile(
+		`(?:/docker/containers/|/docker[/-]|/cri-containerd-|/crio-|/kubepods[^\n]*/)([0-9a-f]{64})\b`)
+)
+
+// runtimeMetadata holds context detected from the runtime environment.
+// Cloud metadata is fetched in the background, so notices sent before it
+// is available are reported without it.
+type runtimeMetadata struct {
+	mu      sync.RWMutex
+	context map[string]interface{}
+
+	done chan struct{}
+}
+
+func newRuntimeMetadata(opt *NotifierOptions) *runtimeMetadata {
+	m := &runtimeMetadata{
+		context: make(map[string]interface{}),
+		done:    make(chan struct{}),
+	}
+	if opt.DetectRuntimeMetadata {
+		m.detectRuntime()
+	}
+	if opt.DetectCloudMetadata {
+		go m.detectCloud()
+	} else {
+		close(m.done)
+	}
+	return m
+}
+
+func (m *runtimeMetadata) detectRuntime() {
+	if k8s := kubernetesMetadata(); len(k8s) > 0 {
+		m.context["kubernetes"] = k8s
+	}
+	if id := containerID(); id != "" {
+		m.context["containerId"] = id
+	}
+}
+
+func (m *runtimeMetadata) detectCloud() {
+	defer close(m.done)
+
+	client := &http.Client{
+		// Metadata servers are link-local and must not be proxied.
+		Transport: &http.Transport{},
+		Timeout:   cloudMetadataTimeout,
+	}
+	for _, fn := range []func(*http.Client) (map[string]interface{}, error){
+		ec2Metadata,
+		gceMetadata,
+	} {
+		cloud, err := fn(client)
+		if err != nil {
+			continue
+		}
+		m.mu.Lock()
+		m.context["cloud"] = cloud
+		m.mu.Unlock()
+		return
+	}
+}
+
+// Filter adds detected context to the notice unless it is already set.
+func (m *runtimeMetadata) Filter(notice *Notice) *Notice {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.context) > 0 && notice.Context == nil {
+		notice.Context = make(map[string]interface{}, len(m.context))
+	}
+	for k, v := range m.context {
+		if _, ok := notice.Context[k]; !ok {
+			notice.Context[k] = v
+		}
+	}
+	return notice
+}
+
+// kubernetesMetadata returns pod metadata exposed with the downward API.
+// Both common naming conventions of the env vars are checked.
+func kubernetesMetadata() map[string]interface{} {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return nil
+	}
+
+	vars := []struct {
+		key   string
+		names []string
+	}{
+		{"namespace", []string{"POD_NAMESPACE", "KUBERNETES_NAMESPACE", "K8S_NAMESPACE"}},
+		{"pod", []string{"POD_NAME", "KUBERNETES_POD_NAME", "K8S_POD_NAME", "HOSTNAME"}},
+		{"podIP", []string{"POD_IP", "KUBERNETES_POD_IP"}},
+		{"node", []string{"NODE_NAME", "KUBERNETES_NODE_NAME", "K8S_NODE_NAME"}},
+		{"serviceAccount", []string{"POD_SERVICE_ACCOUNT", "SERVICE_ACCOUNT"}},
+	}
+	k8s := make(map[string]interface{})
+	for _, v := range vars {
+		for _, name := range v.names {
+			if s := os.Getenv(name); s != "" {
+				k8s[v.key] = s
+				break
+			}
+		}
+	}
+	return k8s
+}
+
+// containerID returns the id of the container the process runs in as
+// found in cgroup v1 paths or cgroup v2 mounts. Only ids in Docker,
+// containerd, CRI-O and Kubernetes paths are matched, because mountinfo
+// also lists overlay layers named with 64 hex digits.
+func containerID() string {
+	for _, filename := range cgroupFiles {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		if m := containerIDRegexp.FindSubmatch(b); m != nil {
+			return string(m[1])
+		}
+	}
+	return ""
+}
+
+type ec2IdentityDocument struct {
+	AccountID        string `json:"accountId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+}
+
+// ec2Metadata returns instance identity using IMDSv2.
+func ec2Metadata(client *http.Client) (map[string]interface{}, error) {
+	req, err := http.NewRequest("PUT", ec2MetadataURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := getMetadata(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = http.NewRequest("GET",
+		ec2MetadataURL+"/latest/dynamic/instance-identity/document", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	b, err := getMetadata(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc ec2IdentityDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"provider":         "aws",
+		"accountId":        doc.AccountID,
+		"region":           doc.Region,
+		"availabilityZone": doc.AvailabilityZone,
+		"instanceId":       doc.InstanceID,
+		"instanceType":     doc.InstanceType,
+	}, nil
+}
+
+type gceInstance struct {
+	ID          json.Number `json:"id"`
+	Zone        string      `json:"zone"`
+	MachineType string      `json:"machineType"`
+}
+
+func gceMetadata(client *http.Client) (map[string]interface{}, error) {
+	req, err := http.NewRequest("GET",
+		gceMetadataURL+"/computeMetadata/v1/instance/?recursive=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	b, err := getMetadata(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var inst gceInstance
+	if err := json.Unmarshal(b, &inst); err != nil {
+		return nil, err
+	}
+
+	// Zone and machine type are reported as resource paths, e.g.
+	// projects/123/zones/us-central1-a.
+	zone := lastPathElem(inst.Zone)
+	region := zone
+	if i := strings.LastIndexByte(zone, '-'); i > 0 {
+		region = zone[:i]
+	}
+	return map[string]interface{}{
+		"provider":         "gcp",
+		"region":           region,
+		"availabilityZone": zone,
+		"instanceId":       inst.ID.String(),
+		"instanceType":     lastPathElem(inst.MachineType),
+	}, nil
+}
+
+func getMetadata(client *http.Client, req *http.Request) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cloudMetadataTimeout)
+	defer cancel()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected response status=%q", resp.Status)
+	}
+	return b, nil
+}
+
+func lastPathElem(s string) string {
+	if i := strings.LastIndexByte(s, '/'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
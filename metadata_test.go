@@ -0,0 +1,173 @@
+package gobrake
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runtimeMetadata", func() {
+	var origEC2, origGCE string
+	var origCgroupFiles []string
+
+	BeforeEach(func() {
+		origEC2, origGCE, origCgroupFiles = ec2MetadataURL, gceMetadataURL, cgroupFiles
+		ec2MetadataURL = "http://localhost:1"
+		gceMetadataURL = "http://localhost:1"
+		cgroupFiles = nil
+	})
+
+	AfterEach(func() {
+		ec2MetadataURL, gceMetadataURL, cgroupFiles = origEC2, origGCE, origCgroupFiles
+	})
+
+	It("detects Kubernetes pod and container id", func() {
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		cgroup := filepath.Join(dir, "cgroup")
+		err = ioutil.WriteFile(cgroup, []byte("0::/kubepods/burstable/pod1/"+id+"\n"), 0600)
+		Expect(err).NotTo(HaveOccurred())
+		cgroupFiles = []string{filepath.Join(dir, "missing"), cgroup}
+
+		for k, v := range map[string]string{
+			"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+			"POD_NAMESPACE":           "billing",
+			"POD_NAME":                "api-7d9f",
+			"NODE_NAME":               "node-1",
+		} {
+			orig, ok := os.LookupEnv(k)
+			os.Setenv(k, v)
+			if ok {
+				defer os.Setenv(k, orig)
+			} else {
+				defer os.Unsetenv(k)
+			}
+		}
+
+		m := newRuntimeMetadata(&NotifierOptions{DetectRuntimeMetadata: true})
+		notice := m.Filter(&Notice{})
+		Expect(notice.Context["containerId"]).To(Equal(id))
+		Expect(notice.Context["kubernetes"]).To(Equal(map[string]interface{}{
+			"namespace": "billing",
+			"pod":       "api-7d9f",
+			"node":      "node-1",
+		}))
+	})
+
+	It("detects container id in mountinfo", func() {
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		const layer = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+		const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		mountinfo := filepath.Join(dir, "mountinfo")
+		err = ioutil.WriteFile(mountinfo, []byte(
+			"644 553 0:52 / / rw,relatime - overlay overlay rw,"+
+				"lowerdir=/var/lib/docker/overlay2/l/ABC,"+
+				"upperdir=/var/lib/docker/overlay2/"+layer+"/diff\n"+
+				"662 644 259:1 /var/lib/docker/containers/"+id+"/resolv.conf "+
+				"/etc/resolv.conf rw,relatime - ext4 /dev/root rw\n"), 0600)
+		Expect(err).NotTo(HaveOccurred())
+		cgroupFiles = []string{mountinfo}
+
+		Expect(containerID()).To(Equal(id))
+	})
+
+	It("detects containerd container id", func() {
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		cgroup := filepath.Join(dir, "cgroup")
+		err = ioutil.WriteFile(cgroup, []byte("0::/kubepods.slice/kubepods-burstable.slice/"+
+			"kubepods-burstable-pod1.slice/cri-containerd-"+id+".scope\n"), 0600)
+		Expect(err).NotTo(HaveOccurred())
+		cgroupFiles = []string{cgroup}
+
+		Expect(containerID()).To(Equal(id))
+	})
+
+	It("detects EC2 instance with IMDSv2", func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			switch req.URL.Path {
+			case "/latest/api/token":
+				Expect(req.Method).To(Equal("PUT"))
+				w.Write([]byte("token"))
+			case "/latest/dynamic/instance-identity/document":
+				Expect(req.Header.Get("X-aws-ec2-metadata-token")).To(Equal("token"))
+				w.Write([]byte(`{"accountId":"123","region":"eu-west-1",` +
+					`"availabilityZone":"eu-west-1a","instanceId":"i-1","instanceType":"t3.micro"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+		ec2MetadataURL = server.URL
+
+		m := newRuntimeMetadata(&NotifierOptions{DetectCloudMetadata: true})
+		Eventually(m.done).Should(BeClosed())
+
+		notice := m.Filter(&Notice{Context: map[string]interface{}{}})
+		Expect(notice.Context["cloud"]).To(Equal(map[string]interface{}{
+			"provider":         "aws",
+			"accountId":        "123",
+			"region":           "eu-west-1",
+			"availabilityZone": "eu-west-1a",
+			"instanceId":       "i-1",
+			"instanceType":     "t3.micro",
+		}))
+	})
+
+	It("detects GCE instance", func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.Header.Get("Metadata-Flavor")).To(Equal("Google"))
+			w.Write([]byte(`{"id":1234567890123456789,` +
+				`"zone":"projects/1/zones/us-central1-a",` +
+				`"machineType":"projects/1/machineTypes/e2-medium"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+		gceMetadataURL = server.URL
+
+		m := newRuntimeMetadata(&NotifierOptions{DetectCloudMetadata: true})
+		Eventually(m.done).Should(BeClosed())
+
+		notice := m.Filter(&Notice{})
+		Expect(notice.Context["cloud"]).To(Equal(map[string]interface{}{
+			"provider":         "gcp",
+			"region":           "us-central1",
+			"availabilityZone": "us-central1-a",
+			"instanceId":       "1234567890123456789",
+			"instanceType":     "e2-medium",
+		}))
+	})
+
+	It("keeps context set on the notice", func() {
+		m := &runtimeMetadata{context: map[string]interface{}{"containerId": "detected"}}
+		notice := m.Filter(&Notice{Context: map[string]interface{}{"containerId": "set"}})
+		Expect(notice.Context["containerId"]).To(Equal("set"))
+	})
+
+	It("is disabled by default", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost:1",
+		})
+		defer notifier.Close()
+		Expect(notifier.metadata).To(BeNil())
+	})
+})
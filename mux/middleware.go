@@ -0,0 +1,131 @@
+package mux
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/gorilla/mux"
+)
+
+// Instrument adds the middleware to the router.
+func Instrument(router *mux.Router, notifier *gobrake.Notifier) {
+	router.Use(NewMiddleware(notifier))
+}
+
+// NewMiddleware returns gorilla/mux middleware that sends requests stats
+// with templated routes, e.g. /users/{id}, instead of raw URLs and
+// reports recovered panics to Airbrake.
+func NewMiddleware(notifier *gobrake.Notifier) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+
+			defer func() {
+				v := recover()
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				if v != nil {
+					notice := notifier.Notice(v, req, 2)
+					notice.Context["severity"] = "critical"
+					notifier.SendNoticeAsync(notice)
+					if !sw.wroteHeader {
+						http.Error(sw, http.StatusText(http.StatusInternalServerError),
+							http.StatusInternalServerError)
+					}
+				}
+
+				info := &gobrake.RequestInfo{
+					Method:     req.Method,
+					Route:      routeTemplate(req),
+					StatusCode: sw.status(),
+					Start:      start,
+					End:        time.Now(),
+				}
+				if notifier.ReportsClientIdentity() {
+					info.ClientIdentity = gobrake.ClientCertIdentity(req)
+				}
+				notifier.NotifyRequest(info)
+			}()
+
+			next.ServeHTTP(sw, req)
+		})
+	}
+}
+
+func routeTemplate(req *http.Request) string {
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return "UNKNOWN"
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "UNKNOWN"
+	}
+	return tpl
+}
+
+// statusWriter records the response status code. It forwards Flush and
+// Hijack, so streaming responses and websocket upgrades keep working.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.code = http.StatusOK
+			w.wroteHeader = true
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. The hijacked request is reported with
+// 101 Switching Protocols status unless a header was already written.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("gobrake: %T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.code = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the original writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) status() int {
+	if !w.wroteHeader {
+		return http.StatusOK
+	}
+	return w.code
+}
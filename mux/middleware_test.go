@@ -0,0 +1,139 @@
+package mux
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"github.com/gorilla/mux"
+)
+
+func newTestRouter(t *testing.T) (*mux.Router, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+
+	router := mux.NewRouter()
+	Instrument(router, notifier)
+	return router, notifier, server
+}
+
+func TestMiddlewareReportsRouteTemplateAndStatus(t *testing.T) {
+	router, notifier, server := newTestRouter(t)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+
+	for _, id := range []string{"1", "2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users/"+id, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("got %d, wanted 404", w.Code)
+		}
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 {
+		t.Fatalf("got routes %+v, wanted 1", routes)
+	}
+	r := routes[0]
+	if r.Method != "GET" || r.Route != "/users/{id}" || r.StatusCode != 404 || r.Count != 2 {
+		t.Errorf("got route %+v", r)
+	}
+}
+
+func TestMiddlewareReportsPanics(t *testing.T) {
+	router, notifier, server := newTestRouter(t)
+	router.HandleFunc("/boom", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/boom", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d, wanted 500", w.Code)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notices[0].Errors[0].Message != "boom" || notices[0].Context["severity"] != "critical" {
+		t.Errorf("got notice %+v", notices[0])
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].StatusCode != 500 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
+
+func TestMiddlewareForwardsFlush(t *testing.T) {
+	router, notifier, server := newTestRouter(t)
+	router.HandleFunc("/stream", func(w http.ResponseWriter, req *http.Request) {
+		w.(http.Flusher).Flush()
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	if !w.Flushed {
+		t.Fatal("response is not flushed")
+	}
+
+	server.Flush(t, notifier)
+	routes := server.Routes()
+	if len(routes) != 1 || routes[0].StatusCode != 200 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
+
+func TestMiddlewareForwardsHijack(t *testing.T) {
+	router, notifier, server := newTestRouter(t)
+	router.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		rw.Flush()
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got %d, wanted 101", resp.StatusCode)
+	}
+
+	// The request is reported after the handler returns.
+	var routes []gobraketest.RouteStat
+	for i := 0; i < 100 && len(routes) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		server.Flush(t, notifier)
+		routes = server.Routes()
+	}
+	if len(routes) != 1 || routes[0].Route != "/ws" || routes[0].StatusCode != 101 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
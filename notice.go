@@ -2,6 +2,7 @@ package gobrake
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"runtime"
@@ -58,6 +59,7 @@ type StackFrame struct {
 
 type Notice struct {
 	Id    string `json:"-"` // id returned by SendNotice
+	URL   string `json:"-"` // permalink returned by SendNotice
 	Error error  `json:"-"` // error returned by SendNotice
 
 	Errors  []Error                `json:"errors"`
@@ -65,6 +67,12 @@ type Notice struct {
 	Env     map[string]interface{} `json:"environment"`
 	Session map[string]interface{} `json:"session"`
 	Params  map[string]interface{} `json:"params"`
+
+	// Project set by NoticeActionRoute.
+	projectId  int64
+	projectKey string
+	// Value the notice was created from.
+	cause interface{}
 }
 
 func (n *Notice) String() string {
@@ -75,15 +83,68 @@ func (n *Notice) String() string {
 	return fmt.Sprintf("Notice<%s: %s>", e.Type, e.Message)
 }
 
+// Retention hints that can be attached to notices with SetRetention.
+const (
+	RetentionKeepLong  = "keep-long"
+	RetentionDebugOnly = "debug-only"
+)
+
+// SetRetention marks the notice with a retention hint which is stored in
+// context/retention so relays and server side policies can decide how
+// long to keep the notice.
+func (n *Notice) SetRetention(hint string) {
+	n.Context["retention"] = hint
+}
+
+// Retention returns the retention hint set with SetRetention.
+func (n *Notice) Retention() string {
+	hint, _ := n.Context["retention"].(string)
+	return hint
+}
+
+// DefaultDeniedHeaders are request headers that carry credentials and
+// are never sent with notices. Custom deny lists are merged with them.
+var DefaultDeniedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+}
+
+// SetRequest fills in the notice context with the request URL, method,
+// user agent, referer and remote address and copies request headers
+// except DefaultDeniedHeaders to the notice environment.
 func (n *Notice) SetRequest(req *http.Request) {
-	n.Context["url"] = req.URL.String()
+	n.SetRequestHeaders(req, nil, DefaultDeniedHeaders)
+}
+
+// SetRequestHeaders is like SetRequest, but only copies headers listed in
+// allow, if it is not empty, and never copies headers listed in deny or
+// DefaultDeniedHeaders. Header names are case-insensitive.
+func (n *Notice) SetRequestHeaders(req *http.Request, allow, deny []string) {
+	n.Context["url"] = requestURL(req)
 	n.Context["httpMethod"] = req.Method
 	if ua := req.Header.Get("User-Agent"); ua != "" {
 		n.Context["userAgent"] = ua
 	}
-	n.Context["userAddr"] = remoteAddr(req)
+	if referer := req.Header.Get("Referer"); referer != "" {
+		n.Context["referer"] = referer
+	}
+	if addr := remoteAddr(req); addr != "" {
+		n.Context["userAddr"] = addr
+	}
 
 	for k, v := range req.Header {
+		if isDeniedHeader(deny, k) {
+			continue
+		}
+		if len(allow) > 0 && !containsHeader(allow, k) {
+			continue
+		}
+
 		if len(v) == 1 {
 			n.Env[k] = v[0]
 		} else {
@@ -92,20 +153,111 @@ func (n *Notice) SetRequest(req *http.Request) {
 	}
 }
 
+// isDeniedHeader reports whether the header is listed in deny or
+// DefaultDeniedHeaders, so credentials are stripped even when a custom
+// deny list does not include them.
+func isDeniedHeader(deny []string, name string) bool {
+	return containsHeader(deny, name) || containsHeader(DefaultDeniedHeaders, name)
+}
+
+// mergeDeniedHeaders returns deny with DefaultDeniedHeaders appended.
+func mergeDeniedHeaders(deny []string) []string {
+	merged := append([]string(nil), deny...)
+	for _, h := range DefaultDeniedHeaders {
+		if !containsHeader(merged, h) {
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+func containsHeader(headers []string, name string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestURL returns the absolute request URL. Servers listening on unix
+// sockets may receive requests without a usable host, in which case only
+// the path is returned.
+func requestURL(req *http.Request) string {
+	if req.URL.IsAbs() {
+		return req.URL.String()
+	}
+
+	host := req.Host
+	if host == "" || strings.ContainsAny(host, "/@") {
+		return req.URL.RequestURI()
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if s := req.Header.Get("X-Forwarded-Proto"); s == "http" || s == "https" {
+		scheme = s
+	}
+
+	u := *req.URL
+	u.Scheme = scheme
+	u.Host = host
+	return u.String()
+}
+
+// remoteAddr returns the client IP address. IPv4-mapped IPv6 addresses
+// are converted to IPv4 and an empty string is returned for unix socket
+// peers which have no address.
 func remoteAddr(req *http.Request) string {
 	if s := req.Header.Get("X-Forwarded-For"); s != "" {
 		parts := strings.Split(s, ",")
-		return parts[0]
+		return normalizeIP(strings.TrimSpace(parts[0]))
 	}
 
 	if s := req.Header.Get("X-Real-Ip"); s != "" {
-		return s
+		return normalizeIP(s)
+	}
+
+	return hostIP(req.RemoteAddr)
+}
+
+// hostIP returns the IP address of host:port address or an empty string
+// if the address has no IP.
+func hostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if net.ParseIP(host) == nil {
+		// Unix socket peers are reported as "@" or an empty string.
+		return ""
 	}
+	return normalizeIP(host)
+}
 
-	parts := strings.Split(req.RemoteAddr, ":")
-	return parts[0]
+func normalizeIP(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return s
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+	return ip.String()
 }
 
+// NewNotice returns a notice for e which can be an error, a string or any
+// other value, e.g. one returned by recover(). The backtrace is taken from
+// errors created with github.com/pkg/errors, otherwise it is captured
+// starting at the caller of NewNotice. depth is the number of frames above
+// the caller to skip, so a helper that wraps NewNotice passes 1 to start
+// the backtrace at its own caller. Notifier and runtime frames on top of
+// the stack are skipped automatically.
+// Params, context and other fields of the returned notice can be changed
+// before it is passed to Notifier.SendNotice. If e is already a notice it
+// is returned as is.
 func NewNotice(e interface{}, req *http.Request, depth int) *Notice {
 	notice, ok := e.(*Notice)
 	if ok {
@@ -113,7 +265,8 @@ func NewNotice(e interface{}, req *http.Request, depth int) *Notice {
 	}
 
 	typeName := getTypeName(e)
-	packageName, backtrace := getBacktrace(e, depth)
+	// getBacktrace and NewNotice itself are skipped too.
+	packageName, funcName, backtrace := getBacktrace(e, depth+2)
 
 	for i := range backtrace {
 		frame := &backtrace[i]
@@ -138,12 +291,17 @@ func NewNotice(e interface{}, req *http.Request, depth int) *Notice {
 		Env:     make(map[string]interface{}),
 		Session: make(map[string]interface{}),
 		Params:  make(map[string]interface{}),
+
+		cause: e,
 	}
 
 	for k, v := range getDefaultContext() {
 		notice.Context[k] = v
 	}
 	notice.Context["component"] = packageName
+	if funcName != "" {
+		notice.Context["action"] = funcName
+	}
 
 	if req != nil {
 		notice.SetRequest(req)
@@ -0,0 +1,117 @@
+package gobrake
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type notifierCtxKey struct{}
+
+// NewContext returns the context that carries the notifier. Libraries can
+// use it with NoticeBuilder to report errors to the notifier configured by
+// the application without depending on a concrete notifier instance.
+func NewContext(ctx context.Context, notifier *Notifier) context.Context {
+	return context.WithValue(ctx, notifierCtxKey{}, notifier)
+}
+
+// FromContext returns the notifier carried by the context or nil.
+func FromContext(ctx context.Context) *Notifier {
+	notifier, _ := ctx.Value(notifierCtxKey{}).(*Notifier)
+	return notifier
+}
+
+// WithoutCancel returns the context that keeps values of the parent
+// context, but is never canceled and has no deadline. It is used to send
+// notices asynchronously after the request that reported them is done.
+func WithoutCancel(ctx context.Context) context.Context {
+	return valuesContext{ctx}
+}
+
+// valuesContext keeps values of the parent context, but is never canceled.
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesContext) Done() <-chan struct{}       { return nil }
+func (valuesContext) Err() error                  { return nil }
+
+// NoticeBuilder constructs a partially filled notice which is sent to
+// the notifier carried by the context.
+type NoticeBuilder struct {
+	notice *Notice
+	req    *http.Request
+}
+
+// NewNoticeBuilder starts building a notice for the error.
+func NewNoticeBuilder(e interface{}) *NoticeBuilder {
+	return &NoticeBuilder{
+		notice: NewNotice(e, nil, 1),
+	}
+}
+
+// Component sets the notice component, e.g. the name of the library.
+func (b *NoticeBuilder) Component(name string) *NoticeBuilder {
+	b.notice.Context["component"] = name
+	return b
+}
+
+// Action sets the notice action.
+func (b *NoticeBuilder) Action(name string) *NoticeBuilder {
+	b.notice.Context["action"] = name
+	return b
+}
+
+// Severity sets the notice severity.
+func (b *NoticeBuilder) Severity(severity string) *NoticeBuilder {
+	b.notice.Context["severity"] = severity
+	return b
+}
+
+// Param adds the param to the notice.
+func (b *NoticeBuilder) Param(key string, value interface{}) *NoticeBuilder {
+	b.notice.Params[key] = value
+	return b
+}
+
+// Context adds the value to the notice context.
+func (b *NoticeBuilder) Context(key string, value interface{}) *NoticeBuilder {
+	b.notice.Context[key] = value
+	return b
+}
+
+// Request sets the request the error happened in. Headers are filtered
+// by the notifier which sends the notice.
+func (b *NoticeBuilder) Request(req *http.Request) *NoticeBuilder {
+	b.req = req
+	return b
+}
+
+// Notice returns the notice built so far. Request is filled in with the
+// DefaultDeniedHeaders.
+func (b *NoticeBuilder) Notice() *Notice {
+	if b.req != nil {
+		b.notice.SetRequest(b.req)
+		b.req = nil
+	}
+	return b.notice
+}
+
+// Send sends the notice asynchronously to the notifier carried by the
+// context. Cancellation of the context does not abort the send, because
+// the context is usually bound to a request which ends before the notice
+// is sent. It reports whether the context carries a notifier.
+func (b *NoticeBuilder) Send(ctx context.Context) bool {
+	notifier := FromContext(ctx)
+	if notifier == nil {
+		return false
+	}
+
+	if b.req != nil {
+		notifier.setRequest(b.notice, b.req)
+		b.req = nil
+	}
+	notifier.SendNoticeAsyncContext(WithoutCancel(ctx), b.notice)
+	return true
+}
@@ -0,0 +1,71 @@
+package gobrake_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NoticeBuilder", func() {
+	var notifier *gobrake.Notifier
+	var sentNotice *gobrake.Notice
+
+	BeforeEach(func() {
+		sentNotice = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+			err = json.Unmarshal(b, &sentNotice)
+			if err != nil {
+				panic(err)
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("sends notice to the notifier carried by context", func() {
+		ctx, cancel := context.WithCancel(gobrake.NewContext(context.Background(), notifier))
+		Expect(gobrake.FromContext(ctx)).To(Equal(notifier))
+
+		ok := gobrake.NewNoticeBuilder(errors.New("query failed")).
+			Component("mylib").
+			Param("query", "SELECT 1").
+			Send(ctx)
+		cancel()
+		Expect(ok).To(BeTrue())
+
+		notifier.Flush()
+		Expect(sentNotice).NotTo(BeNil())
+		Expect(sentNotice.Errors[0].Message).To(Equal("query failed"))
+		Expect(sentNotice.Context["component"]).To(Equal("mylib"))
+		Expect(sentNotice.Params["query"]).To(Equal("SELECT 1"))
+	})
+
+	It("does nothing without notifier", func() {
+		ok := gobrake.NewNoticeBuilder(errors.New("hello")).Send(context.Background())
+		Expect(ok).To(BeFalse())
+	})
+})
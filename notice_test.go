@@ -0,0 +1,207 @@
+package gobrake_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notice", func() {
+	It("carries retention hint in context", func() {
+		notice := gobrake.NewNotice(errors.New("hello"), nil, 0)
+		Expect(notice.Retention()).To(Equal(""))
+
+		notice.SetRetention(gobrake.RetentionDebugOnly)
+		Expect(notice.Retention()).To(Equal("debug-only"))
+		Expect(notice.Context["retention"]).To(Equal("debug-only"))
+	})
+
+	var req *http.Request
+
+	BeforeEach(func() {
+		u, err := url.Parse("http://foo/bar")
+		Expect(err).To(BeNil())
+
+		req = &http.Request{
+			Method:     "GET",
+			URL:        u,
+			RemoteAddr: "10.0.0.1:1234",
+			Header: http.Header{
+				"Authorization": {"Bearer token"},
+				"Cookie":        {"session=1"},
+				"Referer":       {"http://foo/"},
+				"X-Request-Id":  {"123"},
+				"Accept":        {"*/*"},
+			},
+		}
+	})
+
+	It("never sends credential headers by default", func() {
+		notice := gobrake.NewNotice(errors.New("hello"), req, 0)
+
+		Expect(notice.Context["referer"]).To(Equal("http://foo/"))
+		Expect(notice.Context["userAddr"]).To(Equal("10.0.0.1"))
+		Expect(notice.Env).NotTo(HaveKey("Authorization"))
+		Expect(notice.Env).NotTo(HaveKey("Cookie"))
+		Expect(notice.Env["X-Request-Id"]).To(Equal("123"))
+	})
+
+	It("honors notifier headers allowlist and denylist", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			DisableRemoteConfig: true,
+			HeadersAllowlist:    []string{"x-request-id", "accept", "cookie"},
+			HeadersDenylist:     []string{"Cookie"},
+		})
+		defer notifier.Close()
+
+		notice := notifier.Notice(errors.New("hello"), req, 0)
+		Expect(notice.Env).To(Equal(map[string]interface{}{
+			"X-Request-Id": "123",
+			"Accept":       "*/*",
+		}))
+	})
+
+	It("denies credential headers with custom denylist", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			DisableRemoteConfig: true,
+			HeadersDenylist:     []string{"X-Request-Id"},
+		})
+		defer notifier.Close()
+
+		notice := notifier.Notice(errors.New("hello"), req, 0)
+		Expect(notice.Env).To(Equal(map[string]interface{}{
+			"Referer": "http://foo/",
+			"Accept":  "*/*",
+		}))
+
+		details := &gobrake.RequestDetails{
+			Method: "GET",
+			URL:    "http://foo/bar",
+			VisitHeaders: func(fn func(key, value string)) {
+				for k, v := range req.Header {
+					fn(k, v[0])
+				}
+			},
+		}
+		notice = gobrake.NewNotice(errors.New("hello"), nil, 0)
+		notifier.SetNoticeRequest(notice, details)
+		Expect(notice.Env).NotTo(HaveKey("Authorization"))
+		Expect(notice.Env).NotTo(HaveKey("Cookie"))
+		Expect(notice.Env).NotTo(HaveKey("X-Request-Id"))
+	})
+})
+
+var _ = Describe("Notice request address", func() {
+	newRequest := func(target, remoteAddr string) *http.Request {
+		req, err := http.NewRequest("GET", target, nil)
+		Expect(err).To(BeNil())
+		req.URL.Scheme = ""
+		req.URL.Host = ""
+		req.RemoteAddr = remoteAddr
+		return req
+	}
+
+	It("reconstructs URL from Host", func() {
+		req := newRequest("http://example.com/hello?a=1", "[::ffff:10.0.0.1]:1234")
+		notice := gobrake.NewNotice(errors.New("hello"), req, 0)
+
+		Expect(notice.Context["url"]).To(Equal("http://example.com/hello?a=1"))
+		Expect(notice.Context["userAddr"]).To(Equal("10.0.0.1"))
+	})
+
+	It("handles unix socket listeners", func() {
+		req := newRequest("http://example.com/hello", "@")
+		req.Host = ""
+		notice := gobrake.NewNotice(errors.New("hello"), req, 0)
+
+		Expect(notice.Context["url"]).To(Equal("/hello"))
+		Expect(notice.Context).NotTo(HaveKey("userAddr"))
+	})
+
+	It("keeps IPv6 addresses", func() {
+		req := newRequest("http://example.com/hello", "[2001:db8::1]:1234")
+		notice := gobrake.NewNotice(errors.New("hello"), req, 0)
+
+		Expect(notice.Context["userAddr"]).To(Equal("2001:db8::1"))
+	})
+})
+
+var _ = Describe("Notice.SetRequestDetails", func() {
+	It("fills in notice without http.Request", func() {
+		headers := [][2]string{
+			{"User-Agent", "my_user_agent"},
+			{"Authorization", "Bearer token"},
+			{"Accept", "text/html"},
+			{"Accept", "*/*"},
+		}
+		notice := gobrake.NewNotice(errors.New("hello"), nil, 0)
+		notice.SetRequestDetails(&gobrake.RequestDetails{
+			Method:     "GET",
+			URL:        "http://example.com/hello",
+			RemoteAddr: "10.0.0.1:1234",
+			VisitHeaders: func(fn func(key, value string)) {
+				for _, h := range headers {
+					fn(h[0], h[1])
+				}
+			},
+		}, nil, gobrake.DefaultDeniedHeaders)
+
+		Expect(notice.Context["url"]).To(Equal("http://example.com/hello"))
+		Expect(notice.Context["httpMethod"]).To(Equal("GET"))
+		Expect(notice.Context["userAgent"]).To(Equal("my_user_agent"))
+		Expect(notice.Context["userAddr"]).To(Equal("10.0.0.1"))
+		Expect(notice.Env).NotTo(HaveKey("Authorization"))
+		Expect(notice.Env["Accept"]).To(Equal([]string{"text/html", "*/*"}))
+	})
+})
+
+func recoverNotice() (notice *gobrake.Notice) {
+	defer func() {
+		notice = gobrake.NewNotice(recover(), nil, 0)
+	}()
+	panic(errors.New("boom"))
+}
+
+// reportError is a helper that wraps NewNotice and skips its own frame.
+func reportError(err error) *gobrake.Notice {
+	return gobrake.NewNotice(err, nil, 1)
+}
+
+func callReportError() *gobrake.Notice {
+	return reportError(errors.New("boom"))
+}
+
+var _ = Describe("NewNotice", func() {
+	It("starts backtrace at the caller", func() {
+		notice := newComponentNotice()
+		Expect(notice.Errors[0].Type).To(Equal("*errors.errorString"))
+		Expect(notice.Errors[0].Backtrace[0].Func).To(Equal("newComponentNotice"))
+	})
+
+	It("skips depth frames above the caller", func() {
+		notice := callReportError()
+		frames := notice.Errors[0].Backtrace
+		Expect(frames[0].Func).To(Equal("callReportError"))
+		Expect(notice.Context["action"]).To(Equal("callReportError"))
+	})
+
+	It("accepts strings and recovered values", func() {
+		notice := gobrake.NewNotice("hello", nil, 0)
+		Expect(notice.Errors[0].Type).To(Equal("string"))
+		Expect(notice.Errors[0].Message).To(Equal("hello"))
+
+		notice = recoverNotice()
+		Expect(notice.Errors[0].Type).To(Equal("*errors.errorString"))
+		Expect(notice.Errors[0].Message).To(Equal("boom"))
+		Expect(notice.Errors[0].Backtrace[0].Func).To(HavePrefix("recoverNotice"))
+	})
+})
@@ -2,12 +2,16 @@ package gobrake
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"runtime"
@@ -19,6 +23,10 @@ import (
 
 const waitTimeout = 5 * time.Second
 
+const defaultStatsMaxAge = 24 * time.Hour
+
+const defaultTDigestCompression = 20
+
 const httpEnhanceYourCalm = 420
 const httpStatusTooManyRequests = 429
 
@@ -27,10 +35,10 @@ const maxNoticeLen = 64 * 1024
 var (
 	errClosed             = errors.New("gobrake: notifier is closed")
 	errQueueFull          = errors.New("gobrake: queue is full (error is dropped)")
-	errUnauthorized       = errors.New("gobrake: unauthorized: invalid project id or key")
 	errAccountRateLimited = errors.New("gobrake: account is rate limited")
 	errIPRateLimited      = errors.New("gobrake: IP is rate limited")
-	errNoticeTooBig       = errors.New("gobrake: notice exceeds 64KB max size limit")
+	errNoticeTooBig       = &noticeTooBigError{limit: maxNoticeLen}
+	errUnsupportedAPI     = errors.New("gobrake: API endpoint is not supported by the host")
 )
 
 var (
@@ -40,63 +48,309 @@ var (
 
 func defaultHTTPClient() *http.Client {
 	httpClientOnce.Do(func() {
-		httpClient = &http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				Dial: (&net.Dialer{
-					Timeout:   15 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).Dial,
-				TLSHandshakeTimeout: 10 * time.Second,
-				TLSClientConfig: &tls.Config{
-					ClientSessionCache: tls.NewLRUClientSessionCache(1024),
-				},
-				MaxIdleConnsPerHost:   10,
-				ResponseHeaderTimeout: 10 * time.Second,
-			},
-			Timeout: 10 * time.Second,
-		}
+		httpClient, _ = newHTTPClient(&NotifierOptions{})
 	})
 	return httpClient
 }
 
+// newHTTPClient returns the client configured with ProxyURL, TLSConfig,
+// CACertFile and RequestTimeout options. Invalid ProxyURL and unreadable
+// CACertFile are errors, so requests never bypass the configured proxy or
+// fall back to system roots.
+func newHTTPClient(opt *NotifierOptions) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if opt.ProxyURL != "" {
+		u, err := url.Parse(opt.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("gobrake: invalid ProxyURL=%q: %s", opt.ProxyURL, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("gobrake: invalid ProxyURL=%q", opt.ProxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig := &tls.Config{
+		ClientSessionCache: tls.NewLRUClientSessionCache(1024),
+	}
+	if opt.TLSConfig != nil {
+		tlsConfig = opt.TLSConfig.Clone()
+	}
+	if opt.CACertFile != "" {
+		pool, err := loadCACertFile(opt.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("gobrake: CACertFile=%q failed: %s", opt.CACertFile, err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	timeout := 10 * time.Second
+	if opt.RequestTimeout > 0 {
+		timeout = opt.RequestTimeout
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   15 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			TLSClientConfig:       tlsConfig,
+			MaxIdleConnsPerHost:   10,
+			ResponseHeaderTimeout: timeout,
+		},
+		Timeout: timeout,
+	}, nil
+}
+
+func loadCACertFile(name string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificates found")
+	}
+	return pool, nil
+}
+
 var buffers = sync.Pool{
 	New: func() interface{} {
 		return new(bytes.Buffer)
 	},
 }
 
-type filter func(*Notice) *Notice
+type filter func(context.Context, *Notice) *Notice
+
+type requestFilter func(*RequestInfo) *RequestInfo
 
 type NotifierOptions struct {
 	// Airbrake project id.
 	ProjectId int64
 	// Airbrake project key.
 	ProjectKey string
-	// Airbrake host name. Default is https://airbrake.io.
+	// Airbrake host, e.g. https://api.airbrake.io or api.airbrake.io.
+	// Scheme defaults to https. Default is https://api.airbrake.io.
 	Host string
+	// Allow plain http Host and RemoteConfigHost. Loopback hosts are
+	// always allowed.
+	AllowInsecureHost bool
+
+	// Add Kubernetes pod metadata exposed with the downward API and the
+	// container id to notice context.
+	DetectRuntimeMetadata bool
+	// Add cloud instance metadata, e.g. provider, region and instance id,
+	// to notice context. It is fetched from EC2 or GCE metadata servers
+	// in the background on startup.
+	DetectCloudMetadata bool
+
+	// Disable fetching remote config that allows to disable error
+	// notifications or APM and change API hosts without redeploying.
+	DisableRemoteConfig bool
+	// Remote config host. Default is https://notifier-configs.airbrake.io
+	// when Host is the default Airbrake host, otherwise remote config is
+	// not fetched.
+	RemoteConfigHost string
+	// File where the last fetched remote config is cached so it is
+	// applied on startup. Remote config is not cached when empty.
+	RemoteConfigCacheFile string
 
-	// Environment such as production or development.
+	// Environment such as production or development. Default is detected
+	// from AIRBRAKE_ENVIRONMENT, APP_ENV, GO_ENV, ENVIRONMENT, ENV and
+	// RACK_ENV environment variables.
 	Environment string
-	// Git revision. Default is SOURCE_VERSION on Heroku.
+	// Environments, e.g. development or test-*, in which notices and
+	// requests stats are not sent. Glob patterns are supported.
+	DisabledEnvironments []string
+
+	// Compatibility mode for self-hosted Errbit, which implements only
+	// the notices API: APM, remote config and check-ins are disabled.
+	Errbit bool
+
+	// Disable error notifications for APM-only deployments.
+	DisableErrorNotifications bool
+	// Disable APM: requests stats, route breakdowns and queries stats are
+	// neither aggregated nor sent.
+	DisableAPM bool
+	// Disable requests stats, but keep route breakdowns.
+	DisableRouteStats bool
+	// Aggregate requests stats and breakdowns by status code class, e.g.
+	// 201 and 204 are reported as 200 and 404 as 400, to reduce number of
+	// routes for APIs that use many status codes.
+	StatusCodeClasses bool
+	// Requests stats and breakdowns older than StatsMaxAge are dropped
+	// instead of being sent, because the API rejects or misattributes
+	// stale data. Default is 24 hours. Negative value disables expiry.
+	StatsMaxAge time.Duration
+	// Derive minute buckets of requests stats and breakdowns from a
+	// monotonic clock synced with the Date header of the first Airbrake
+	// API response instead of the host clock, and clamp future buckets to
+	// the current minute. Useful on hosts with clock skew.
+	ClockSkewCorrection bool
+	// Compression of t-digests used to summarize requests durations.
+	// Higher values give more accurate quantiles at the cost of bigger
+	// payloads. Default is 20.
+	TDigestCompression uint32
+	// Upper bounds in milliseconds of fixed histogram buckets. When set,
+	// requests durations are counted into the buckets instead of
+	// t-digests, which is cheaper for services with high request rates.
+	// Bounds are copied, sorted and deduplicated.
+	StatsHistogramBuckets []float64
+	// Git revision. Default is SOURCE_VERSION on Heroku or, when
+	// DetectRuntimeMetadata is set, the VCS revision from build info.
 	Revision string
+	// Context merged into every notice, e.g. hostname, region, pod or
+	// version. The values override library defaults such as hostname,
+	// but values set by the caller are kept. The values are also exported
+	// as resource attributes of OTLP metrics.
+	DefaultContext map[string]interface{}
+	// Number of consecutive delivery failures (transport errors and 5xx
+	// responses) after which notices and stats are dropped for
+	// BreakerCooldown. The circuit breaker is disabled when zero.
+	BreakerThreshold int
+	// How long data is dropped once the circuit breaker opens. Then a
+	// single request is sent to probe whether Airbrake is reachable again.
+	// Default is 1 minute.
+	BreakerCooldown time.Duration
+	// Called when the circuit breaker opens or closes, e.g. to export its
+	// state to metrics.
+	OnBreakerStateChange func(open bool)
+	// File where process starts are recorded to detect crash loops. When
+	// the process restarts more than CrashLoopThreshold times within
+	// CrashLoopWindow, Notifier.ReportCrashLoop sends a single crash-loop
+	// notice with the last panic recovered by NotifyOnPanic. Crash loop
+	// detection is disabled when empty.
+	CrashLoopFile string
+	// Default is 5 restarts.
+	CrashLoopThreshold int
+	// Default is 10 minutes.
+	CrashLoopWindow time.Duration
+	// Number of the last reported notices kept in memory and returned by
+	// RecentNotices. Disabled when zero.
+	RecentNoticesLimit int
+	// Rules evaluated before notices are sent. The first matching rule
+	// decides whether the notice is dropped, sampled, routed to another
+	// project or escalated.
+	NoticeRules []NoticeRule
+	// Overrides component and action that are detected from the top
+	// in-app frame of the backtrace. Empty values keep the detected ones.
+	ComponentActionFunc func(notice *Notice) (component, action string)
+	// Request headers that are sent with notices. All headers except
+	// HeadersDenylist are sent when empty.
+	HeadersAllowlist []string
+	// Request headers that are never sent with notices in addition to
+	// DefaultDeniedHeaders, which are always denied.
+	HeadersDenylist []string
+	// Report identity of the verified TLS client certificate as the notice
+	// user and use RequestInfo.ClientIdentity as a requests stats
+	// dimension. Useful for mTLS-authenticated APIs.
+	ReportClientCertIdentity bool
+	// Max size of the encoded notice in bytes. Bigger notices lose code
+	// hunks, then params, session and environment values are limited to
+	// depth 5, 1024 bytes strings and 128 items and finally dropped before
+	// the notice is rejected. Default and maximum is 64KB.
+	NoticeMaxSize int
+	// Limits of params, session and environment values applied to every
+	// notice: nesting depth, string length and number of map or slice
+	// items. Values are not limited when zero or negative, which is the
+	// default.
+	NoticeMaxDepth     int
+	NoticeMaxStringLen int
+	NoticeMaxItems     int
+	// Max number of backtrace frames per error. Default is 100.
+	NoticeMaxBacktrace int
+	// Errors that are never reported: sentinel errors such as
+	// context.Canceled, error types as reflect.Type, e.g.
+	// reflect.TypeOf(&net.OpError{}), or interface types, regexps and
+	// substrings of messages such as "broken pipe". Wrapped errors are
+	// matched too. Ignored errors are dropped before filters are run and,
+	// with Notify, before notices are created.
+	IgnoredErrors []interface{}
 	// List of keys containing sensitive information that must be filtered out.
 	// Default is password, secret.
 	KeysBlacklist []interface{}
 
-	// http.Client that is used to interact with Airbrake API.
+	// http.Client that is used to interact with Airbrake API. ProxyURL,
+	// TLSConfig, CACertFile and RequestTimeout are ignored when set.
 	HTTPClient *http.Client
+	// Proxy used to reach Airbrake API. Default is the proxy from
+	// HTTP_PROXY and HTTPS_PROXY environment variables. Notices and stats
+	// are not sent when it is invalid.
+	ProxyURL string
+	// TLS config used to reach Airbrake API, e.g. with client certificates.
+	TLSConfig *tls.Config
+	// PEM file with CA certificates trusted instead of system roots, e.g.
+	// for an on-premise Errbit or Airbrake endpoint. Notices and stats are
+	// not sent when it can not be loaded.
+	CACertFile string
+	// Timeout of a single request to Airbrake API. Default is 10 seconds.
+	RequestTimeout time.Duration
+	// Shared secret used to sign notices and stats payloads with
+	// HMAC-SHA256, so a relay can verify them with VerifySignature.
+	// Payloads are not signed when empty.
+	SigningKey []byte
+
+	// Options of a second backend, e.g. a new account or region during a
+	// migration, that receives a copy of every notice, requests stats,
+	// route breakdown and check-in. It has its own circuit breaker, rate
+	// limits and filters, and its failures are logged without affecting
+	// the first backend.
+	DualWrite *NotifierOptions
+
+	// Logger that is used to report internal diagnostics such as failed
+	// sends. Default is the logger set with SetLogger.
+	Logger Logger
+	// Log a summary line after each requests stats flush: number of routes
+	// and requests, top-3 slowest routes and the send result.
+	LogFlushSummary bool
+
+	// OTLP/HTTP metrics endpoint, e.g. http://localhost:4318/v1/metrics.
+	// When set, route stats are also exported as OpenTelemetry
+	// exponential histograms.
+	OTLPMetricsURL string
+	// Extra headers sent with OTLP requests, e.g. authentication tokens.
+	OTLPHeaders map[string]string
+
+	// StatsD or DogStatsD address, e.g. 127.0.0.1:8125. When set, route
+	// timings are mirrored to StatsD alongside the Airbrake upload.
+	StatsdAddr string
+	// Prefix prepended to StatsD metric names.
+	StatsdPrefix string
+	// Use DogStatsD tags instead of encoding route in the metric name.
+	StatsdDogTags bool
+
+	clock *statsClock
 }
 
 func (opt *NotifierOptions) init() {
 	if opt.Host == "" {
-		opt.Host = "https://api.airbrake.io"
+		opt.Host = defaultHost
+	}
+
+	if opt.Errbit {
+		opt.DisableAPM = true
+		opt.DisableRemoteConfig = true
+	}
+
+	if opt.RemoteConfigHost == "" && opt.Host == defaultHost {
+		opt.RemoteConfigHost = defaultRemoteConfigHost
+	}
+
+	if opt.Environment == "" {
+		opt.Environment = detectEnvironment()
 	}
 
 	if opt.Revision == "" {
 		// https://devcenter.heroku.com/changelog-items/630
 		opt.Revision = os.Getenv("SOURCE_VERSION")
 	}
+	if opt.Revision == "" && opt.DetectRuntimeMetadata {
+		opt.Revision = buildRevision()
+	}
 
 	if opt.KeysBlacklist == nil {
 		opt.KeysBlacklist = []interface{}{
@@ -105,48 +359,187 @@ func (opt *NotifierOptions) init() {
 		}
 	}
 
+	opt.HeadersDenylist = mergeDeniedHeaders(opt.HeadersDenylist)
+
 	if opt.HTTPClient == nil {
 		opt.HTTPClient = defaultHTTPClient()
 	}
+
+	if opt.StatsMaxAge == 0 {
+		opt.StatsMaxAge = defaultStatsMaxAge
+	}
+	if opt.ClockSkewCorrection && opt.clock == nil {
+		opt.clock = newStatsClock()
+	}
+
+	if opt.CrashLoopThreshold == 0 {
+		opt.CrashLoopThreshold = defaultCrashLoopThreshold
+	}
+	if opt.CrashLoopWindow == 0 {
+		opt.CrashLoopWindow = defaultCrashLoopWindow
+	}
+
+	if opt.BreakerCooldown == 0 {
+		opt.BreakerCooldown = defaultBreakerCooldown
+	}
+
+	if opt.TDigestCompression == 0 {
+		opt.TDigestCompression = defaultTDigestCompression
+	}
+	if len(opt.StatsHistogramBuckets) > 0 {
+		opt.StatsHistogramBuckets = histogramBounds(opt.StatsHistogramBuckets)
+	}
+
+	if opt.NoticeMaxSize <= 0 || opt.NoticeMaxSize > maxNoticeLen {
+		opt.NoticeMaxSize = maxNoticeLen
+	}
+	if opt.NoticeMaxBacktrace <= 0 {
+		opt.NoticeMaxBacktrace = defaultNoticeMaxBacktrace
+	}
+}
+
+// statsExpired reports whether stats collected at tm are older than
+// StatsMaxAge.
+func (opt *NotifierOptions) statsExpired(tm time.Time) bool {
+	return opt.StatsMaxAge > 0 && opt.clock.Now().Sub(tm) > opt.StatsMaxAge
+}
+
+func (opt *NotifierOptions) logger() Logger {
+	if opt.Logger != nil {
+		return opt.Logger
+	}
+	return logger
 }
 
 type Notifier struct {
-	opt             *NotifierOptions
-	createNoticeURL string
+	opt          *NotifierOptions
+	remoteConfig *remoteConfig
+	breaker      *circuitBreaker
+	metrics      *notifierMetrics
+	recent       *recentNotices
+	disabled     bool
+	hostErr      error
+	metadata     *runtimeMetadata
+	ignored      *ignoredErrors
+	secondary    *Notifier
 
-	filters []filter
+	filters        []filter
+	requestFilters []requestFilter
+
+	defaultMu     sync.RWMutex
+	defaultParams map[string]interface{}
 
 	inFlight int32 // atomic
 	limit    chan struct{}
 	wg       sync.WaitGroup
 
-	routes *routeStats
+	routes     *routeStats
+	breakdowns *routeBreakdowns
+	queries    *queryStats
+	hosts      *hostStats
+
+	onceMu   sync.Mutex
+	onceSent map[string]time.Time
 
 	rateLimitReset uint32 // atomic
 	_closed        uint32 // atomic
+	shuttingDown   uint32 // atomic
+
+	abandonedNotices uint32 // atomic
+	abandonedRoutes  uint32 // atomic
+}
+
+// initHTTPClient creates the client when ProxyURL, TLSConfig, CACertFile
+// or RequestTimeout is set and HTTPClient is not.
+func (opt *NotifierOptions) initHTTPClient() error {
+	if opt.HTTPClient != nil {
+		return nil
+	}
+	if opt.ProxyURL == "" && opt.TLSConfig == nil && opt.CACertFile == "" &&
+		opt.RequestTimeout <= 0 {
+		return nil
+	}
+
+	client, err := newHTTPClient(opt)
+	if err != nil {
+		return err
+	}
+	opt.HTTPClient = client
+	return nil
 }
 
 func NewNotifierWithOptions(opt *NotifierOptions) *Notifier {
+	hostErr := opt.normalizeHosts()
+	if hostErr == nil {
+		hostErr = opt.initHTTPClient()
+	}
+	if hostErr != nil {
+		opt.logger().Printf("NewNotifierWithOptions failed: %s", hostErr)
+	}
 	opt.init()
 
+	var rc *remoteConfig
+	if !opt.DisableRemoteConfig && opt.RemoteConfigHost != "" && hostErr == nil {
+		rc = newRemoteConfig(opt)
+	}
+
+	var breaker *circuitBreaker
+	if opt.BreakerThreshold > 0 {
+		breaker = newCircuitBreaker(opt)
+	}
+
 	n := &Notifier{
-		opt: opt,
-		createNoticeURL: fmt.Sprintf("%s/api/v3/projects/%d/notices",
-			opt.Host, opt.ProjectId),
+		opt:          opt,
+		remoteConfig: rc,
+		breaker:      breaker,
+		metrics:      &notifierMetrics{},
+		disabled:     opt.environmentDisabled(),
+		hostErr:      hostErr,
+		ignored:      newIgnoredErrors(opt.IgnoredErrors),
 
 		limit: make(chan struct{}, 2*runtime.NumCPU()),
 
-		routes: newRouteStats(opt),
+		routes:     newRouteStats(opt, rc),
+		breakdowns: newRouteBreakdowns(opt, rc),
+		queries:    newQueryStats(opt, rc),
+		hosts:      new(hostStats),
+	}
+	if opt.DualWrite != nil && opt.DualWrite != opt {
+		n.secondary = NewNotifierWithOptions(opt.DualWrite)
+	}
+	if opt.RecentNoticesLimit > 0 {
+		n.recent = newRecentNotices(opt.RecentNoticesLimit)
 	}
+	n.routes.breaker = breaker
+	n.routes.metrics = n.metrics
+	n.breakdowns.breaker = breaker
+	n.breakdowns.metrics = n.metrics
+	n.queries.breaker = breaker
+	n.queries.metrics = n.metrics
+	rc.Start()
 
 	n.AddFilter(newNotifierFilter(n))
+	n.AddFilter(newShutdownFilter(n))
 	n.AddFilter(gopathFilter)
 	n.AddFilter(gitFilter)
 
+	if opt.ComponentActionFunc != nil {
+		n.AddFilter(newComponentActionFilter(opt.ComponentActionFunc))
+	}
+
+	if opt.DetectRuntimeMetadata || opt.DetectCloudMetadata {
+		n.metadata = newRuntimeMetadata(opt)
+		n.AddFilter(n.metadata.Filter)
+	}
+
 	if len(opt.KeysBlacklist) > 0 {
 		n.AddFilter(NewBlacklistKeysFilter(opt.KeysBlacklist...))
 	}
 
+	if opt.CrashLoopFile != "" {
+		n.recordStart()
+	}
+
 	return n
 }
 
@@ -157,120 +550,300 @@ func NewNotifier(projectId int64, projectKey string) *Notifier {
 	})
 }
 
+// AddDefaultParam adds the param to every notice that does not set it
+// already.
+func (n *Notifier) AddDefaultParam(key string, value interface{}) {
+	n.defaultMu.Lock()
+	if n.defaultParams == nil {
+		n.defaultParams = make(map[string]interface{})
+	}
+	n.defaultParams[key] = value
+	n.defaultMu.Unlock()
+
+	if n.secondary != nil {
+		n.secondary.AddDefaultParam(key, value)
+	}
+}
+
+func (n *Notifier) mergeDefaultParams(notice *Notice) {
+	n.defaultMu.RLock()
+	defer n.defaultMu.RUnlock()
+
+	if len(n.defaultParams) == 0 {
+		return
+	}
+	if notice.Params == nil {
+		notice.Params = make(map[string]interface{}, len(n.defaultParams))
+	}
+	for k, v := range n.defaultParams {
+		if _, ok := notice.Params[k]; !ok {
+			notice.Params[k] = v
+		}
+	}
+}
+
 // AddFilter adds filter that can change notice or ignore it by returning nil.
 func (n *Notifier) AddFilter(fn func(*Notice) *Notice) {
+	n.filters = append(n.filters, func(_ context.Context, notice *Notice) *Notice {
+		return fn(notice)
+	})
+}
+
+// AddContextFilter is like AddFilter, but the filter also receives the
+// context the notice is sent with, e.g. to extract trace ids.
+func (n *Notifier) AddContextFilter(fn func(context.Context, *Notice) *Notice) {
 	n.filters = append(n.filters, fn)
 }
 
+// AddRequestFilter adds filter that can change request info passed to
+// NotifyRequest or ignore it by returning nil.
+func (n *Notifier) AddRequestFilter(fn func(*RequestInfo) *RequestInfo) {
+	n.requestFilters = append(n.requestFilters, fn)
+}
+
 // Notify notifies Airbrake about the error.
 func (n *Notifier) Notify(e interface{}, req *http.Request) {
+	if !n.errorsEnabled() || n.ignored.Match(e) {
+		return
+	}
 	notice := n.Notice(e, req, 1)
 	n.SendNoticeAsync(notice)
 }
 
+// NotifyContext is like Notify, but the notice is sent with the context
+// which bounds the send and is passed to context filters.
+func (n *Notifier) NotifyContext(ctx context.Context, e interface{}, req *http.Request) {
+	if !n.errorsEnabled() || n.ignored.Match(e) {
+		return
+	}
+	notice := n.Notice(e, req, 1)
+	n.SendNoticeAsyncContext(ctx, notice)
+}
+
 // Notice returns Aibrake notice created from error and request. depth
 // determines which call frame to use when constructing backtrace.
 func (n *Notifier) Notice(err interface{}, req *http.Request, depth int) *Notice {
-	return NewNotice(err, req, depth+3)
+	notice := NewNotice(err, nil, depth+1)
+	if _, ok := err.(*Notice); ok {
+		return notice
+	}
+	if req != nil {
+		n.setRequest(notice, req)
+	}
+	return notice
+}
+
+// setRequest fills in the notice with the request using notifier options.
+func (n *Notifier) setRequest(notice *Notice, req *http.Request) {
+	notice.SetRequestHeaders(req, n.opt.HeadersAllowlist, n.opt.HeadersDenylist)
+	if n.opt.ReportClientCertIdentity {
+		notice.setClientCertUser(req)
+	}
 }
 
 type sendResponse struct {
-	Id string `json:"id"`
+	Id  string `json:"id"`
+	URL string `json:"url"`
 }
 
-// SendNotice sends notice to Airbrake.
+// SendNotice sends notice to Airbrake and returns the id of the created
+// notice. The id and the permalink URL of the notice are also set as
+// notice.Id and notice.URL.
 func (n *Notifier) SendNotice(notice *Notice) (string, error) {
+	return n.SendNoticeContext(context.Background(), notice)
+}
+
+// SendNoticeContext is like SendNotice, but the request is bound to the
+// context.
+func (n *Notifier) SendNoticeContext(ctx context.Context, notice *Notice) (string, error) {
 	if n.closed() {
+		n.metrics.noticeDropped()
 		return "", errClosed
 	}
-	return n.sendNotice(notice)
+	return n.sendNotice(ctx, notice)
 }
 
-func (n *Notifier) sendNotice(notice *Notice) (string, error) {
+func (n *Notifier) sendNotice(ctx context.Context, notice *Notice) (string, error) {
+	start := time.Now()
+	resp, err := n.doSendNotice(ctx, notice)
+	if resp == nil {
+		resp = new(sendResponse)
+	}
+	notice.Id, notice.URL = resp.Id, resp.URL
+	n.metrics.notice(resp.Id, err, time.Since(start))
+	if resp.Id != "" || err != nil {
+		n.recent.add(notice, resp, err)
+	}
+	return resp.Id, err
+}
+
+func (n *Notifier) doSendNotice(ctx context.Context, notice *Notice) (*sendResponse, error) {
+	if !n.errorsEnabled() {
+		// Notice is ignored.
+		return nil, nil
+	}
+	if n.hostErr != nil {
+		return nil, n.hostErr
+	}
+	if !n.remoteConfig.ErrorsEnabled() {
+		return nil, errErrorsDisabledRemotely
+	}
+
+	if n.ignored.Match(notice.cause) {
+		// Notice is ignored.
+		return nil, nil
+	}
+
 	for _, fn := range n.filters {
-		notice = fn(notice)
+		notice = fn(ctx, notice)
 		if notice == nil {
 			// Notice is ignored.
-			return "", nil
+			return nil, nil
 		}
 	}
 
+	notice = n.applyNoticeRules(notice)
+	if notice == nil {
+		// Notice is ignored.
+		return nil, nil
+	}
+
+	n.dualWriteNotice(ctx, notice)
+
 	if time.Now().Unix() < int64(atomic.LoadUint32(&n.rateLimitReset)) {
-		return "", errIPRateLimited
+		return nil, errIPRateLimited
 	}
 
 	buf := buffers.Get().(*bytes.Buffer)
 	defer buffers.Put(buf)
 
+	n.opt.truncateNotice(notice)
+
 	buf.Reset()
 	err := json.NewEncoder(buf).Encode(notice)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if buf.Len() > maxNoticeLen {
-		return "", errNoticeTooBig
+	for buf.Len() > n.opt.NoticeMaxSize {
+		if !shrinkNotice(notice, buf.Len()) {
+			if n.opt.NoticeMaxSize == maxNoticeLen {
+				return nil, errNoticeTooBig
+			}
+			return nil, &noticeTooBigError{limit: n.opt.NoticeMaxSize}
+		}
+
+		buf.Reset()
+		err = json.NewEncoder(buf).Encode(notice)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	req, err := http.NewRequest("POST", n.createNoticeURL, buf)
+	projectId, projectKey := n.opt.ProjectId, n.opt.ProjectKey
+	if notice.projectId != 0 {
+		projectId, projectKey = notice.projectId, notice.projectKey
+	}
+
+	createNoticeURL := fmt.Sprintf("%s/api/v3/projects/%d/notices",
+		n.remoteConfig.ErrorHost(n.opt), projectId)
+	req, err := http.NewRequest("POST", createNoticeURL, buf)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	req = req.WithContext(ctx)
+	req, trace := withSendTrace(req)
 
-	req.Header.Set("Authorization", "Bearer "+n.opt.ProjectKey)
+	req.Header.Set("Authorization", "Bearer "+projectKey)
 	req.Header.Set("Content-Type", "application/json")
+	n.opt.signRequest(req, buf.Bytes())
+	if !n.breaker.Allow() {
+		return nil, errBreakerOpen
+	}
 	resp, err := n.opt.HTTPClient.Do(req)
+	n.breaker.Done(ctx, resp, err)
 	if err != nil {
-		return "", err
+		err = &sendError{err: err, trace: trace}
+		n.opt.logger().Printf("SendNotice failed reporting notice=%q: %s", notice, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
+	n.opt.clock.Sync(resp)
 
 	buf.Reset()
 	_, err = buf.ReadFrom(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		var sendResp sendResponse
-		err = json.NewDecoder(buf).Decode(&sendResp)
+		sendResp := new(sendResponse)
+		err = json.NewDecoder(buf).Decode(sendResp)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		return sendResp.Id, nil
+		return sendResp, nil
 	}
 
 	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return "", errUnauthorized
 	case httpStatusTooManyRequests:
 		delayStr := resp.Header.Get("X-RateLimit-Delay")
 		delay, err := strconv.ParseInt(delayStr, 10, 64)
 		if err == nil {
 			atomic.StoreUint32(&n.rateLimitReset, uint32(time.Now().Unix()+delay))
 		}
-		return "", errIPRateLimited
+		return nil, errIPRateLimited
 	case httpEnhanceYourCalm:
-		return "", errAccountRateLimited
+		return nil, errAccountRateLimited
 	}
 
-	err = fmt.Errorf("got unexpected response status=%q", resp.Status)
-	logger.Printf("SendNotice failed reporting notice=%q: %s", notice, err)
-	return "", err
+	err = newResponseError(resp, buf.Bytes())
+	n.opt.logger().Printf("SendNotice failed reporting notice=%q: %s", notice, err)
+	return nil, err
 }
 
 // SendNoticeAsync is like SendNotice, but sends notice asynchronously.
 // Pending notices can be flushed with Flush.
 func (n *Notifier) SendNoticeAsync(notice *Notice) {
+	n.SendNoticeAsyncContext(context.Background(), notice)
+}
+
+// SendNoticeAsyncContext is like SendNoticeAsync, but the request is
+// bound to the context.
+func (n *Notifier) SendNoticeAsyncContext(ctx context.Context, notice *Notice) {
+	n.sendNoticeAsync(ctx, notice, nil)
+}
+
+// SendNoticeAsyncCallback is like SendNoticeAsyncContext, but calls
+// callback with the id and the permalink URL of the created notice once
+// it is sent, or with the error if it was not. Callback is also called
+// when the notice is dropped, so it is called exactly once.
+func (n *Notifier) SendNoticeAsyncCallback(
+	ctx context.Context, notice *Notice, callback func(id, url string, err error),
+) {
+	n.sendNoticeAsync(ctx, notice, callback)
+}
+
+func (n *Notifier) sendNoticeAsync(
+	ctx context.Context, notice *Notice, callback func(id, url string, err error),
+) {
 	if n.closed() {
+		n.metrics.noticeDropped()
 		notice.Error = errClosed
+		if callback != nil {
+			callback("", "", notice.Error)
+		}
 		return
 	}
 
 	inFlight := atomic.AddInt32(&n.inFlight, 1)
 	if inFlight > 1000 {
 		atomic.AddInt32(&n.inFlight, -1)
+		n.metrics.noticeDropped()
 		notice.Error = errQueueFull
+		if callback != nil {
+			callback("", "", notice.Error)
+		}
 		return
 	}
 
@@ -278,7 +851,10 @@ func (n *Notifier) SendNoticeAsync(notice *Notice) {
 	go func() {
 		n.limit <- struct{}{}
 
-		notice.Id, notice.Error = n.sendNotice(notice)
+		_, notice.Error = n.sendNotice(ctx, notice)
+		if callback != nil {
+			callback(notice.Id, notice.URL, notice.Error)
+		}
 		atomic.AddInt32(&n.inFlight, -1)
 		n.wg.Done()
 
@@ -290,6 +866,7 @@ func (n *Notifier) SendNoticeAsync(notice *Notice) {
 // with defer statement.
 func (n *Notifier) NotifyOnPanic() {
 	if v := recover(); v != nil {
+		n.spoolPanic(v)
 		notice := n.Notice(v, nil, 3)
 		notice.Context["severity"] = "critical"
 		n.SendNotice(notice)
@@ -300,6 +877,9 @@ func (n *Notifier) NotifyOnPanic() {
 // Flush waits for pending requests to finish.
 func (n *Notifier) Flush() {
 	n.waitTimeout(waitTimeout)
+	if n.secondary != nil {
+		n.secondary.Flush()
+	}
 }
 
 func (n *Notifier) Close() error {
@@ -311,7 +891,104 @@ func (n *Notifier) CloseTimeout(timeout time.Duration) error {
 	if !atomic.CompareAndSwapUint32(&n._closed, 0, 1) {
 		return nil
 	}
-	return n.waitTimeout(timeout)
+	n.remoteConfig.Stop()
+	err := n.waitTimeout(timeout)
+	n.routes.Close()
+	n.dualWrite("CloseTimeout", func(s *Notifier) error {
+		return s.CloseTimeout(timeout)
+	})
+	return err
+}
+
+// CloseWithTimeout closes the notifier flushing pending notices and
+// requests stats in parallel. Unlike CloseTimeout it guarantees to return
+// within timeout: whatever is not sent by then is abandoned and counted.
+func (n *Notifier) CloseWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return n.CloseContext(ctx)
+}
+
+// CloseContext is like CloseWithTimeout, but returns when the context
+// is done. Requests stats that are being sent are canceled.
+func (n *Notifier) CloseContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapUint32(&n._closed, 0, 1) {
+		return nil
+	}
+	n.remoteConfig.Stop()
+	err := n.flushContext(ctx)
+	n.routes.Close()
+	n.dualWrite("CloseContext", func(s *Notifier) error {
+		return s.CloseContext(ctx)
+	})
+	return err
+}
+
+// FlushContext waits for pending notices and sends collected requests
+// stats. It returns when everything is sent or when the context is done.
+func (n *Notifier) FlushContext(ctx context.Context) error {
+	err := n.flushContext(ctx)
+	n.dualWrite("FlushContext", func(s *Notifier) error {
+		return s.FlushContext(ctx)
+	})
+	return err
+}
+
+func (n *Notifier) flushContext(ctx context.Context) error {
+	notices := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(notices)
+	}()
+
+	m, dropped := n.routes.swap()
+	bm := n.breakdowns.swap()
+	qm := n.queries.swap()
+	routes := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			n.routes.sendAll(ctx, m, dropped)
+			wg.Done()
+		}()
+		go func() {
+			n.breakdowns.sendAll(ctx, bm)
+			wg.Done()
+		}()
+		go func() {
+			n.queries.sendAll(ctx, qm)
+			wg.Done()
+		}()
+		wg.Wait()
+		close(routes)
+	}()
+
+	for notices != nil || routes != nil {
+		select {
+		case <-notices:
+			notices = nil
+		case <-routes:
+			routes = nil
+		case <-ctx.Done():
+			var abandonedNotices, abandonedRoutes int
+			if notices != nil {
+				abandonedNotices = int(atomic.LoadInt32(&n.inFlight))
+				atomic.AddUint32(&n.abandonedNotices, uint32(abandonedNotices))
+			}
+			if routes != nil {
+				abandonedRoutes = len(m) + len(bm) + len(qm)
+				atomic.AddUint32(&n.abandonedRoutes, uint32(abandonedRoutes))
+			}
+			err := fmt.Errorf("gobrake: flush aborted: %s "+
+				"(abandoned notices=%d routes=%d)",
+				ctx.Err(), abandonedNotices, abandonedRoutes)
+			n.opt.logger().Printf("flushContext failed: %s", err)
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (n *Notifier) closed() bool {
@@ -335,5 +1012,88 @@ func (n *Notifier) waitTimeout(timeout time.Duration) error {
 
 // NotifyRequest notifies Airbrake about the request.
 func (n *Notifier) NotifyRequest(req *RequestInfo) error {
+	n.dualWrite("NotifyRequest", func(s *Notifier) error {
+		cp := *req
+		return s.NotifyRequest(&cp)
+	})
+
+	if !n.apmEnabled() {
+		return nil
+	}
+
+	if req.ClientIdentity != "" && !n.opt.ReportClientCertIdentity {
+		cp := *req
+		cp.ClientIdentity = ""
+		req = &cp
+	}
+
+	req = n.filterRequest(req)
+	if req == nil {
+		return nil
+	}
+	return n.notifyRouteStats(req)
+}
+
+func (n *Notifier) notifyRouteStats(req *RequestInfo) error {
+	if n.opt.DisableRouteStats {
+		return nil
+	}
 	return n.routes.NotifyRequest(req)
 }
+
+func (n *Notifier) apmEnabled() bool {
+	return !n.disabled && !n.opt.DisableAPM && n.hostErr == nil &&
+		n.remoteConfig.APMEnabled()
+}
+
+func (n *Notifier) errorsEnabled() bool {
+	return !n.disabled && !n.opt.DisableErrorNotifications
+}
+
+// NotifyRouteMetric notifies Airbrake about the request and the breakdown
+// of its duration into groups. End is set to the current time if it is zero.
+func (n *Notifier) NotifyRouteMetric(metric *RouteMetric) error {
+	n.dualWrite("NotifyRouteMetric", func(s *Notifier) error {
+		return s.NotifyRouteMetric(metric)
+	})
+
+	if !n.apmEnabled() {
+		return nil
+	}
+
+	if metric.End.IsZero() {
+		metric.End = time.Now()
+	}
+
+	req := n.filterRequest(&RequestInfo{
+		Method:     metric.Method,
+		Route:      metric.Route,
+		StatusCode: metric.StatusCode,
+		Start:      metric.Start,
+		End:        metric.End,
+	})
+	if req == nil {
+		return nil
+	}
+
+	err := n.notifyRouteStats(req)
+	if err != nil {
+		return err
+	}
+
+	if req.Start.IsZero() || req.End.Before(req.Start) {
+		return nil
+	}
+	return n.breakdowns.Notify(n.opt.routeKey(req), req.End.Sub(req.Start), metric.Groups())
+}
+
+func (n *Notifier) filterRequest(req *RequestInfo) *RequestInfo {
+	for _, fn := range n.requestFilters {
+		req = fn(req)
+		if req == nil {
+			// Request is ignored.
+			return nil
+		}
+	}
+	return req
+}
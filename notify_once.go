@@ -0,0 +1,44 @@
+package gobrake
+
+import (
+	"time"
+)
+
+// NotifyOnce is like Notify, but reports the error at most once per
+// process lifetime for the key. It is useful for recurring conditions,
+// e.g. an optional dependency being unreachable in a retry loop. It
+// reports whether the notice was queued. The key is marked when the
+// notice is queued, so a notice that fails to send is not retried.
+func (n *Notifier) NotifyOnce(key string, e interface{}) bool {
+	return n.notifyOnce(key, 0, e)
+}
+
+// NotifyEvery is like NotifyOnce, but reports the error again once the
+// interval passes.
+func (n *Notifier) NotifyEvery(key string, interval time.Duration, e interface{}) bool {
+	return n.notifyOnce(key, interval, e)
+}
+
+func (n *Notifier) notifyOnce(key string, interval time.Duration, e interface{}) bool {
+	if !n.errorsEnabled() {
+		return false
+	}
+
+	now := n.opt.clock.Now()
+	n.onceMu.Lock()
+	last, ok := n.onceSent[key]
+	if ok && (interval <= 0 || now.Sub(last) < interval) {
+		n.onceMu.Unlock()
+		return false
+	}
+	if n.onceSent == nil {
+		n.onceSent = make(map[string]time.Time)
+	}
+	n.onceSent[key] = now
+	n.onceMu.Unlock()
+
+	notice := n.Notice(e, nil, 2)
+	notice.Context["notifyOnceKey"] = key
+	n.SendNoticeAsync(notice)
+	return true
+}
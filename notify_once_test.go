@@ -0,0 +1,50 @@
+package gobrake_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NotifyOnce", func() {
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+	var sent int32
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&sent, 0)
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&sent, 1)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		server.Close()
+	})
+
+	It("reports error once per key", func() {
+		err := errors.New("cache is unreachable")
+		Expect(notifier.NotifyOnce("cache", err)).To(BeTrue())
+		Expect(notifier.NotifyOnce("cache", err)).To(BeFalse())
+		Expect(notifier.NotifyOnce("queue", err)).To(BeTrue())
+
+		notifier.Flush()
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(2)))
+	})
+})
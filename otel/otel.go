@@ -0,0 +1,54 @@
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTraceFilter returns context filter that attaches trace and span ids
+// of the active span to the notice context. It should be added with
+// Notifier.AddContextFilter and notices must be sent with a context,
+// e.g. using Notifier.NotifyContext.
+func NewTraceFilter() func(context.Context, *gobrake.Notice) *gobrake.Notice {
+	return func(ctx context.Context, notice *gobrake.Notice) *gobrake.Notice {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return notice
+		}
+
+		if notice.Context == nil {
+			notice.Context = make(map[string]interface{})
+		}
+		notice.Context["traceId"] = sc.TraceID().String()
+		notice.Context["spanId"] = sc.SpanID().String()
+		return notice
+	}
+}
+
+// NewRequestFilter returns request filter that records route durations
+// to the http.server.duration histogram created using meter. It should be
+// added with Notifier.AddRequestFilter.
+func NewRequestFilter(meter metric.Meter) (func(*gobrake.RequestInfo) *gobrake.RequestInfo, error) {
+	hist, err := meter.Float64Histogram("http.server.duration",
+		metric.WithUnit("ms"),
+		metric.WithDescription("Duration of HTTP server requests."))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *gobrake.RequestInfo) *gobrake.RequestInfo {
+		ms := float64(req.End.Sub(req.Start)) / float64(time.Millisecond)
+		hist.Record(context.Background(), ms, metric.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.route", req.Route),
+			attribute.Int("http.status_code", req.StatusCode),
+		))
+		return req
+	}, nil
+}
@@ -0,0 +1,122 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var testSpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+	TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+	SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	TraceFlags: trace.FlagsSampled,
+})
+
+func TestTraceFilterAddsSpanIDs(t *testing.T) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	notifier.AddContextFilter(NewTraceFilter())
+
+	ctx := trace.ContextWithSpanContext(context.Background(), testSpanContext)
+	notifier.NotifyContext(ctx, errors.New("boom"), nil)
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if got := notice.Context["traceId"]; got != "0102030405060708090a0b0c0d0e0f10" {
+		t.Fatalf("got traceId %v", got)
+	}
+	if got := notice.Context["spanId"]; got != "0102030405060708" {
+		t.Fatalf("got spanId %v", got)
+	}
+}
+
+func TestTraceFilterSkipsContextsWithoutSpan(t *testing.T) {
+	filter := NewTraceFilter()
+
+	contexts := map[string]context.Context{
+		"spanless": context.Background(),
+		"invalid":  trace.ContextWithSpanContext(context.Background(), trace.SpanContext{}),
+	}
+	for name, ctx := range contexts {
+		notice := gobrake.NewNotice(errors.New("boom"), nil, 0)
+		notice = filter(ctx, notice)
+		if _, ok := notice.Context["traceId"]; ok {
+			t.Fatalf("%s: got traceId %v", name, notice.Context["traceId"])
+		}
+		if _, ok := notice.Context["spanId"]; ok {
+			t.Fatalf("%s: got spanId %v", name, notice.Context["spanId"])
+		}
+	}
+}
+
+type testHistogram struct {
+	noop.Float64Histogram
+	value float64
+	attrs attribute.Set
+}
+
+func (h *testHistogram) Record(ctx context.Context, value float64, opts ...metric.RecordOption) {
+	h.value = value
+	h.attrs = metric.NewRecordConfig(opts).Attributes()
+}
+
+type testMeter struct {
+	noop.Meter
+	name string
+	hist *testHistogram
+}
+
+func (m *testMeter) Float64Histogram(
+	name string, opts ...metric.Float64HistogramOption,
+) (metric.Float64Histogram, error) {
+	m.name = name
+	return m.hist, nil
+}
+
+func TestRequestFilterRecordsDuration(t *testing.T) {
+	meter := &testMeter{hist: new(testHistogram)}
+	filter, err := NewRequestFilter(meter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	req := &gobrake.RequestInfo{
+		Method:     "GET",
+		Route:      "/hello/:name",
+		StatusCode: 200,
+		Start:      start,
+		End:        start.Add(1500 * time.Microsecond),
+	}
+	if filter(req) != req {
+		t.Fatal("filter did not return the request")
+	}
+
+	if meter.name != "http.server.duration" {
+		t.Fatalf("got histogram %q", meter.name)
+	}
+	if meter.hist.value != 1.5 {
+		t.Fatalf("got duration %v, wanted 1.5", meter.hist.value)
+	}
+	wanted := attribute.NewSet(
+		attribute.String("http.method", "GET"),
+		attribute.String("http.route", "/hello/:name"),
+		attribute.Int("http.status_code", 200),
+	)
+	if !meter.hist.attrs.Equals(&wanted) {
+		t.Fatalf("got attributes %v", meter.hist.attrs.ToSlice())
+	}
+}
@@ -0,0 +1,307 @@
+package gobrake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	otlpMaxScale      = 20
+	otlpMaxBucketsLen = 160
+)
+
+// expHistogram is a base-2 exponential histogram as defined by the
+// OpenTelemetry data model. The scale is lowered automatically when the
+// number of buckets exceeds otlpMaxBucketsLen.
+type expHistogram struct {
+	scale     int
+	offset    int
+	buckets   []uint64
+	zeroCount uint64
+
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func newExpHistogram() *expHistogram {
+	return &expHistogram{
+		scale: otlpMaxScale,
+	}
+}
+
+func (h *expHistogram) Add(v float64) {
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if h.count == 0 || v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+
+	if v <= 0 {
+		h.zeroCount++
+		return
+	}
+
+	ind := h.index(v)
+	if len(h.buckets) == 0 {
+		h.offset = ind
+		h.buckets = append(h.buckets, 1)
+		return
+	}
+
+	low, high := h.offset, h.offset+len(h.buckets)-1
+	if ind < low {
+		low = ind
+	}
+	if ind > high {
+		high = ind
+	}
+
+	var change uint
+	for (high>>change)-(low>>change)+1 > otlpMaxBucketsLen {
+		change++
+	}
+	if change > 0 {
+		h.downscale(change)
+		ind >>= change
+	}
+
+	h.grow(ind)
+	h.buckets[ind-h.offset]++
+}
+
+// index returns the bucket index for the positive value v at the current
+// scale. Bucket i covers the range (base^i, base^(i+1)].
+func (h *expHistogram) index(v float64) int {
+	scaleFactor := math.Ldexp(math.Log2E, h.scale)
+	return int(math.Ceil(math.Log(v)*scaleFactor)) - 1
+}
+
+func (h *expHistogram) downscale(change uint) {
+	offset := h.offset >> change
+	buckets := make([]uint64, ((h.offset+len(h.buckets)-1)>>change)-offset+1)
+	for i, c := range h.buckets {
+		buckets[((h.offset+i)>>change)-offset] += c
+	}
+
+	h.scale -= int(change)
+	h.offset = offset
+	h.buckets = buckets
+}
+
+func (h *expHistogram) grow(ind int) {
+	if ind < h.offset {
+		buckets := make([]uint64, h.offset-ind+len(h.buckets))
+		copy(buckets[h.offset-ind:], h.buckets)
+		h.offset = ind
+		h.buckets = buckets
+		return
+	}
+
+	if end := h.offset + len(h.buckets); ind >= end {
+		h.buckets = append(h.buckets, make([]uint64, ind-end+1)...)
+	}
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int64) otlpKeyValue {
+	s := strconv.FormatInt(value, 10)
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+type otlpBuckets struct {
+	Offset       int      `json:"offset"`
+	BucketCounts []string `json:"bucketCounts"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	Count             string         `json:"count"`
+	Sum               float64        `json:"sum"`
+	Scale             int            `json:"scale"`
+	ZeroCount         string         `json:"zeroCount"`
+	Positive          otlpBuckets    `json:"positive"`
+	Min               float64        `json:"min"`
+	Max               float64        `json:"max"`
+}
+
+type otlpExponentialHistogram struct {
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name                 string                   `json:"name"`
+	Unit                 string                   `json:"unit"`
+	ExponentialHistogram otlpExponentialHistogram `json:"exponentialHistogram"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   map[string]string `json:"scope"`
+	Metrics []otlpMetric      `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     map[string][]otlpKeyValue `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics        `json:"scopeMetrics"`
+}
+
+type otlpMetricsJSONRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// otlpAggregationTemporalityDelta is AGGREGATION_TEMPORALITY_DELTA.
+const otlpAggregationTemporalityDelta = 1
+
+// otlpExporter converts route stats into OTLP exponential histograms and
+// pushes them to an OTLP/HTTP endpoint using JSON encoding.
+type otlpExporter struct {
+	opt *NotifierOptions
+}
+
+func newOTLPExporter(opt *NotifierOptions) *otlpExporter {
+	return &otlpExporter{
+		opt: opt,
+	}
+}
+
+func (e *otlpExporter) dataPoint(key routeKey, h *expHistogram) otlpDataPoint {
+	counts := make([]string, len(h.buckets))
+	for i, c := range h.buckets {
+		counts[i] = strconv.FormatUint(c, 10)
+	}
+
+	return otlpDataPoint{
+		Attributes: []otlpKeyValue{
+			otlpString("http.method", key.Method),
+			otlpString("http.route", key.Route),
+			otlpInt("http.status_code", int64(key.StatusCode)),
+		},
+		StartTimeUnixNano: strconv.FormatInt(key.Time().UnixNano(), 10),
+		TimeUnixNano:      strconv.FormatInt(key.Time().Add(time.Minute).UnixNano(), 10),
+		Count:             strconv.FormatUint(h.count, 10),
+		Sum:               h.sum,
+		Scale:             h.scale,
+		ZeroCount:         strconv.FormatUint(h.zeroCount, 10),
+		Positive: otlpBuckets{
+			Offset:       h.offset,
+			BucketCounts: counts,
+		},
+		Min: h.min,
+		Max: h.max,
+	}
+}
+
+func (e *otlpExporter) send(ctx context.Context, m map[routeKey]*routeStat) error {
+	var points []otlpDataPoint
+	for k, v := range m {
+		if v.hist == nil {
+			continue
+		}
+		points = append(points, e.dataPoint(k, v.hist))
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	resource := []otlpKeyValue{
+		otlpString("telemetry.sdk.name", "gobrake"),
+	}
+	if e.opt.Environment != "" {
+		resource = append(resource, otlpString("deployment.environment", e.opt.Environment))
+	}
+	keys := make([]string, 0, len(e.opt.DefaultContext))
+	for k := range e.opt.DefaultContext {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource = append(resource, otlpString(k, fmt.Sprint(e.opt.DefaultContext[k])))
+	}
+
+	jsonReq := otlpMetricsJSONRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: map[string][]otlpKeyValue{
+				"attributes": resource,
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope: map[string]string{
+					"name": "github.com/airbrake/gobrake",
+				},
+				Metrics: []otlpMetric{{
+					Name: "http.server.duration",
+					Unit: "ms",
+					ExponentialHistogram: otlpExponentialHistogram{
+						AggregationTemporality: otlpAggregationTemporalityDelta,
+						DataPoints:             points,
+					},
+				}},
+			}},
+		}},
+	}
+
+	buf := buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+
+	buf.Reset()
+	err := json.NewEncoder(buf).Encode(jsonReq)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.opt.OTLPMetricsURL, buf)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req, trace := withSendTrace(req)
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opt.OTLPHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.opt.HTTPClient.Do(req)
+	if err != nil {
+		return &sendError{err: err, trace: trace}
+	}
+	defer resp.Body.Close()
+
+	buf.Reset()
+	_, err = buf.ReadFrom(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("got unexpected response status=%q", resp.Status)
+	return err
+}
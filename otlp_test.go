@@ -0,0 +1,93 @@
+package gobrake
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("expHistogram", func() {
+	It("counts values into exponential buckets", func() {
+		h := newExpHistogram()
+		for _, v := range []float64{0, 1, 2, 4, 100} {
+			h.Add(v)
+		}
+
+		Expect(h.count).To(Equal(uint64(5)))
+		Expect(h.sum).To(Equal(107.0))
+		Expect(h.zeroCount).To(Equal(uint64(1)))
+		Expect(h.min).To(Equal(0.0))
+		Expect(h.max).To(Equal(100.0))
+
+		var total uint64
+		for _, c := range h.buckets {
+			total += c
+		}
+		Expect(total).To(Equal(uint64(4)))
+	})
+
+	It("downscales to keep buckets bounded", func() {
+		h := newExpHistogram()
+		for v := 0.001; v < 1e6; v *= 1.5 {
+			h.Add(v)
+		}
+
+		Expect(len(h.buckets)).To(BeNumerically("<=", otlpMaxBucketsLen))
+		Expect(h.scale).To(BeNumerically("<", otlpMaxScale))
+
+		base := math.Pow(2, math.Pow(2, -float64(h.scale)))
+		lower := math.Pow(base, float64(h.offset))
+		upper := math.Pow(base, float64(h.offset+len(h.buckets)))
+		Expect(lower).To(BeNumerically("<", 0.001))
+		Expect(upper).To(BeNumerically(">=", h.max))
+	})
+})
+
+var _ = Describe("otlpExporter", func() {
+	It("exports default context as resource attributes", func() {
+		var sentReq otlpMetricsJSONRequest
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			err := json.NewDecoder(req.Body).Decode(&sentReq)
+			Expect(err).NotTo(HaveOccurred())
+			w.WriteHeader(http.StatusOK)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		opt := &NotifierOptions{
+			OTLPMetricsURL: server.URL,
+			Environment:    "production",
+			DefaultContext: map[string]interface{}{
+				"region":  "eu-west-1",
+				"replica": 3,
+			},
+		}
+		opt.init()
+
+		stat := newRouteStat(opt)
+		stat.hist = newExpHistogram()
+		Expect(stat.Add(12)).NotTo(HaveOccurred())
+
+		e := newOTLPExporter(opt)
+		err := e.send(context.Background(), map[routeKey]*routeStat{
+			{Method: "GET", Route: "/hello", StatusCode: 200}: stat,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var attrs []string
+		for _, kv := range sentReq.ResourceMetrics[0].Resource["attributes"] {
+			attrs = append(attrs, kv.Key+"="+*kv.Value.StringValue)
+		}
+		Expect(attrs).To(Equal([]string{
+			"telemetry.sdk.name=gobrake",
+			"deployment.environment=production",
+			"region=eu-west-1",
+			"replica=3",
+		}))
+	})
+})
@@ -0,0 +1,239 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueryInfo describes a datastore query, e.g. an SQL statement or a Redis
+// command, made while serving the route.
+type QueryInfo struct {
+	Method string
+	Route  string
+	Query  string
+	Func   string
+	File   string
+	Line   int
+	Start  time.Time
+	End    time.Time
+}
+
+// queryKey identifies stats of a query in a minute bucket.
+type queryKey struct {
+	Method string
+	Route  string
+	Query  string
+	Func   string
+	File   string
+	Line   int
+	Minute int64 // unix time in minutes
+}
+
+// queryKeyJSON is the queryKey representation used by Airbrake API.
+type queryKeyJSON struct {
+	Method string    `json:"method"`
+	Route  string    `json:"route"`
+	Query  string    `json:"query"`
+	Func   string    `json:"function"`
+	File   string    `json:"file"`
+	Line   int       `json:"line"`
+	Time   time.Time `json:"time"`
+}
+
+func (opt *NotifierOptions) queryKey(q *QueryInfo) queryKey {
+	key := queryKey{
+		Method: q.Method,
+		Route:  q.Route,
+		Query:  q.Query,
+		Func:   q.Func,
+		File:   q.File,
+		Line:   q.Line,
+		Minute: unixMinute(q.End),
+	}
+	if opt.clock != nil {
+		key.Minute = unixMinute(opt.clock.Bucket(q.End))
+	}
+	return key
+}
+
+func (k queryKey) json() queryKeyJSON {
+	return queryKeyJSON{
+		Method: k.Method,
+		Route:  k.Route,
+		Query:  k.Query,
+		Func:   k.Func,
+		File:   k.File,
+		Line:   k.Line,
+		Time:   time.Unix(k.Minute*60, 0).UTC(),
+	}
+}
+
+// intern returns the key with interned strings.
+func (k queryKey) intern() queryKey {
+	k.Method = routeStrings.Intern(k.Method)
+	k.Route = routeStrings.Intern(k.Route)
+	k.Query = routeStrings.Intern(k.Query)
+	return k
+}
+
+type queryKeyStat struct {
+	queryKeyJSON
+	*routeStat
+}
+
+// queryStats aggregates durations of datastore queries and periodically
+// sends collected data to Airbrake.
+type queryStats struct {
+	opt          *NotifierOptions
+	remoteConfig *remoteConfig
+	breaker      *circuitBreaker
+	metrics      *notifierMetrics
+	unsupported  unsupportedStream
+
+	// mu is read-locked while a query is added and write-locked when the
+	// flush window is swapped, so queries are never added to stats that
+	// are being sent.
+	mu sync.RWMutex
+	m  map[queryKey]*routeStat
+
+	flushTimer *time.Timer
+}
+
+func newQueryStats(opt *NotifierOptions, rc *remoteConfig) *queryStats {
+	return &queryStats{
+		opt:          opt,
+		remoteConfig: rc,
+		unsupported:  unsupportedStream{name: "queryStats"},
+	}
+}
+
+func (s *queryStats) init() {
+	if s.m == nil && s.flushTimer == nil {
+		s.m = make(map[queryKey]*routeStat)
+		s.flushTimer = time.AfterFunc(flushPeriod, s.flush)
+	}
+}
+
+func (s *queryStats) flush() {
+	m := s.swap()
+	s.sendAll(context.Background(), m)
+}
+
+// swap returns collected stats and resets the flush window.
+func (s *queryStats) swap() map[queryKey]*routeStat {
+	s.mu.Lock()
+
+	m := s.m
+	s.m = nil
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+
+	s.mu.Unlock()
+
+	return m
+}
+
+func (s *queryStats) sendAll(ctx context.Context, m map[queryKey]*routeStat) {
+	if len(m) == 0 || s.unsupported.Disabled() {
+		return
+	}
+
+	start := time.Now()
+	err := s.send(ctx, m)
+	s.metrics.flush(err, time.Since(start))
+	if err != nil && !s.unsupported.Check(s.opt, err) {
+		s.opt.logger().Printf("queryStats.send failed: %s", err)
+	}
+}
+
+type queriesStatsJSONRequest struct {
+	Queries []queryKeyStat `json:"queries"`
+}
+
+func (s *queryStats) send(ctx context.Context, m map[queryKey]*routeStat) error {
+	var queries []queryKeyStat
+	for k, v := range m {
+		v.mu.Lock()
+		if s.opt.statsExpired(time.Unix(k.Minute*60, 0)) {
+			s.metrics.expired(v.Count)
+			v.mu.Unlock()
+			continue
+		}
+
+		err := v.compress()
+		v.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		queries = append(queries, queryKeyStat{
+			queryKeyJSON: k.json(),
+			routeStat:    v,
+		})
+	}
+
+	jsonReq := queriesStatsJSONRequest{
+		Queries: queries,
+	}
+	apiURL := fmt.Sprintf("%s/api/v5/projects/%d/queries-stats",
+		s.remoteConfig.APMHost(s.opt), s.opt.ProjectId)
+	return sendStats(ctx, s.opt, s.breaker, apiURL, jsonReq)
+}
+
+func (s *queryStats) Notify(q *QueryInfo) error {
+	if s.unsupported.Disabled() {
+		return nil
+	}
+	if q.Start.IsZero() || q.End.Before(q.Start) {
+		return nil
+	}
+
+	key := s.opt.queryKey(q)
+
+	stat := s.rlockStat(key)
+	defer s.mu.RUnlock()
+
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.Add(durationMs(q.End.Sub(q.Start)))
+}
+
+// rlockStat read-locks s.mu and returns the stat of the key in the current
+// flush window, creating it if needed. The caller must call s.mu.RUnlock.
+func (s *queryStats) rlockStat(key queryKey) *routeStat {
+	s.mu.RLock()
+	for {
+		if stat, ok := s.m[key]; ok {
+			return stat
+		}
+		s.mu.RUnlock()
+
+		s.mu.Lock()
+		s.init()
+		if _, ok := s.m[key]; !ok {
+			s.m[key.intern()] = newRouteStat(s.opt)
+		}
+		s.mu.Unlock()
+
+		s.mu.RLock()
+	}
+}
+
+// NotifyQuery notifies Airbrake about the datastore query. Queries stats
+// are reported separately from requests stats, grouped by the route the
+// query was made in.
+func (n *Notifier) NotifyQuery(q *QueryInfo) error {
+	n.dualWrite("NotifyQuery", func(s *Notifier) error {
+		cp := *q
+		return s.NotifyQuery(&cp)
+	})
+
+	if !n.apmEnabled() {
+		return nil
+	}
+	return n.queries.Notify(q)
+}
@@ -0,0 +1,86 @@
+package gobrake_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notifier.NotifyQuery", func() {
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+	var mu sync.Mutex
+	var paths []string
+	var queries []map[string]interface{}
+
+	BeforeEach(func() {
+		paths, queries = nil, nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			var body struct {
+				Queries []map[string]interface{} `json:"queries"`
+			}
+			Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+
+			mu.Lock()
+			paths = append(paths, req.URL.Path)
+			queries = append(queries, body.Queries...)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		server.Close()
+	})
+
+	It("sends queries stats separately from requests stats", func() {
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			err := notifier.NotifyQuery(&gobrake.QueryInfo{
+				Method: "GET",
+				Route:  "/users",
+				Query:  "SELECT * FROM users",
+				Start:  start,
+				End:    start.Add(10 * time.Millisecond),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(notifier.FlushContext(context.Background())).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{"/api/v5/projects/1/queries-stats"}))
+		Expect(queries).To(HaveLen(1))
+		Expect(queries[0]["method"]).To(Equal("GET"))
+		Expect(queries[0]["route"]).To(Equal("/users"))
+		Expect(queries[0]["query"]).To(Equal("SELECT * FROM users"))
+		Expect(queries[0]["count"]).To(Equal(2.0))
+		Expect(queries[0]["sum"]).To(Equal(20.0))
+	})
+
+	It("ignores queries with invalid duration", func() {
+		err := notifier.NotifyQuery(&gobrake.QueryInfo{
+			Query: "SELECT 1",
+			End:   time.Now(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(notifier.FlushContext(context.Background())).NotTo(HaveOccurred())
+		Expect(paths).To(BeEmpty())
+	})
+})
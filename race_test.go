@@ -1,11 +1,15 @@
 package gobrake_test
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 
 	"github.com/airbrake/gobrake"
 )
@@ -21,9 +25,10 @@ var _ = Describe("Notifier", func() {
 		server := httptest.NewServer(http.HandlerFunc(handler))
 
 		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
-			ProjectId:  1,
-			ProjectKey: "key",
-			Host:       server.URL,
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
 		})
 	})
 
@@ -40,4 +45,44 @@ var _ = Describe("Notifier", func() {
 
 		notifier.Flush()
 	})
+
+	It("flushes breakdowns and queries while they are updated", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					// New group names write to the breakdown groups map.
+					_, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+					metric.AddGroup(fmt.Sprintf("group%d-%d", i, j), time.Millisecond)
+					metric.End = metric.Start.Add(2 * time.Millisecond)
+					Expect(notifier.NotifyRouteMetric(metric)).To(Succeed())
+
+					start := time.Now()
+					Expect(notifier.NotifyQuery(&gobrake.QueryInfo{
+						Route: "/hello",
+						Query: "SELECT 1",
+						Start: start,
+						End:   start.Add(time.Millisecond),
+					})).To(Succeed())
+				}
+			}(i)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		for flushing := true; flushing; {
+			select {
+			case <-done:
+				flushing = false
+			default:
+			}
+			Expect(notifier.FlushContext(context.Background())).To(Succeed())
+		}
+	})
 })
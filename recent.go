@@ -0,0 +1,82 @@
+package gobrake
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentNotice contains metadata of a notice reported by the notifier.
+type RecentNotice struct {
+	Id       string    `json:"id,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Type     string    `json:"type"`
+	Message  string    `json:"message"`
+	Severity string    `json:"severity,omitempty"`
+	Time     time.Time `json:"time"`
+	// Error returned when the notice was sent, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// recentNotices is a ring buffer of the last reported notices.
+type recentNotices struct {
+	mu    sync.Mutex
+	ring  []RecentNotice
+	next  int
+	count int
+}
+
+func newRecentNotices(limit int) *recentNotices {
+	return &recentNotices{
+		ring: make([]RecentNotice, limit),
+	}
+}
+
+func (r *recentNotices) add(notice *Notice, resp *sendResponse, err error) {
+	if r == nil {
+		return
+	}
+
+	rn := RecentNotice{
+		Id:   resp.Id,
+		URL:  resp.URL,
+		Time: time.Now(),
+	}
+	if len(notice.Errors) > 0 {
+		rn.Type = notice.Errors[0].Type
+		rn.Message = notice.Errors[0].Message
+	}
+	rn.Severity, _ = notice.Context["severity"].(string)
+	if err != nil {
+		rn.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.ring[r.next] = rn
+	r.next = (r.next + 1) % len(r.ring)
+	if r.count < len(r.ring) {
+		r.count++
+	}
+	r.mu.Unlock()
+}
+
+func (r *recentNotices) list() []RecentNotice {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notices := make([]RecentNotice, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		ind := (r.next - 1 - i + len(r.ring)) % len(r.ring)
+		notices = append(notices, r.ring[ind])
+	}
+	return notices
+}
+
+// RecentNotices returns metadata of the last reported notices, newest
+// first. It returns nil unless NotifierOptions.RecentNoticesLimit is set.
+func (n *Notifier) RecentNotices() []RecentNotice {
+	return n.recent.list()
+}
@@ -0,0 +1,65 @@
+package gobrake_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecentNotices", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("keeps the last notices newest first", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:          1,
+			ProjectKey:         "key",
+			Host:               server.URL,
+			RecentNoticesLimit: 2,
+		})
+		defer notifier.Close()
+
+		for _, msg := range []string{"first", "second", "third"} {
+			notice := notifier.Notice(errors.New(msg), nil, 0)
+			notice.Context["severity"] = "warning"
+			_, err := notifier.SendNotice(notice)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		recent := notifier.RecentNotices()
+		Expect(recent).To(HaveLen(2))
+		Expect(recent[0].Message).To(Equal("third"))
+		Expect(recent[0].Id).To(Equal("123"))
+		Expect(recent[0].Severity).To(Equal("warning"))
+		Expect(recent[1].Message).To(Equal("second"))
+	})
+
+	It("is disabled by default", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+		defer notifier.Close()
+
+		notifier.Notify(errors.New("hello"), nil)
+		notifier.Flush()
+		Expect(notifier.RecentNotices()).To(BeNil())
+	})
+})
@@ -0,0 +1,77 @@
+//go:build go1.18
+// +build go1.18
+
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/redis/go-redis/v9"
+)
+
+// Hook is a go-redis hook that adds command latency to the "redis" group
+// of the route metric carried by the command context and, when created
+// with a notifier, reports per-command queries stats.
+//
+//	rdb.AddHook(gobrakeredis.NewHook(notifier))
+type Hook struct {
+	notifier *gobrake.Notifier
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns the hook. Per-command stats are reported as queries
+// stats of the route carried by the command context, where the query is
+// the command name, when notifier is not nil.
+func NewHook(notifier *gobrake.Notifier) *Hook {
+	return &Hook{
+		notifier: notifier,
+	}
+}
+
+// DialHook implements redis.Hook. Dial time is not attributed to commands.
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.record(ctx, cmd.Name(), start)
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.record(ctx, "pipeline", start)
+		return err
+	}
+}
+
+func (h *Hook) record(ctx context.Context, name string, start time.Time) {
+	end := time.Now()
+	metric := gobrake.ContextRouteMetric(ctx)
+	metric.AddGroup("redis", end.Sub(start))
+
+	if h.notifier == nil {
+		return
+	}
+
+	q := &gobrake.QueryInfo{
+		Query: name,
+		Start: start,
+		End:   end,
+	}
+	if metric != nil {
+		q.Method, q.Route = metric.Method, metric.Route
+	}
+	h.notifier.NotifyQuery(q)
+}
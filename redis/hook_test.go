@@ -0,0 +1,122 @@
+//go:build go1.18
+// +build go1.18
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestHook(t *testing.T) (*Hook, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	return NewHook(notifier), notifier, server
+}
+
+func TestHookRecordsCommands(t *testing.T) {
+	hook, notifier, server := newTestHook(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/users/:id")
+
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	for i := 0; i < 2; i++ {
+		if err := process(ctx, redis.NewStringCmd(ctx, "get", "user:1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := metric.Groups()["redis"]; got < 2*time.Millisecond {
+		t.Errorf("got redis group %s, wanted at least 2ms", got)
+	}
+
+	server.Flush(t, notifier)
+	if routes := server.Routes(); len(routes) != 0 {
+		t.Fatalf("got route stats %v, wanted none", routes)
+	}
+	queries := server.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("got %d queries, wanted 1", len(queries))
+	}
+	q := queries[0]
+	if q.Method != "GET" || q.Route != "/users/:id" || q.Query != "get" || q.Count != 2 {
+		t.Errorf("got query %+v", q)
+	}
+}
+
+func TestHookRecordsFailedCommands(t *testing.T) {
+	hook, notifier, server := newTestHook(t)
+	ctx := context.Background()
+
+	wanted := errors.New("connection refused")
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return wanted
+	})
+	if err := process(ctx, redis.NewStringCmd(ctx, "set", "key", "value")); err != wanted {
+		t.Fatalf("got %v, wanted %v", err, wanted)
+	}
+
+	server.Flush(t, notifier)
+	if routes := server.Routes(); len(routes) != 0 {
+		t.Fatalf("got route stats %v, wanted none", routes)
+	}
+	queries := server.Queries()
+	if len(queries) != 1 || queries[0].Query != "set" || queries[0].Route != "" {
+		t.Fatalf("got queries %+v", queries)
+	}
+}
+
+func TestHookRecordsPipelines(t *testing.T) {
+	hook, notifier, server := newTestHook(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "POST", "/orders")
+
+	var got int
+	process := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		got = len(cmds)
+		return nil
+	})
+	cmds := []redis.Cmder{
+		redis.NewStatusCmd(ctx, "set", "a", "1"),
+		redis.NewIntCmd(ctx, "incr", "b"),
+	}
+	if err := process(ctx, cmds); err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Fatalf("got %d commands, wanted 2", got)
+	}
+	if _, ok := metric.Groups()["redis"]; !ok {
+		t.Error("redis group is not recorded")
+	}
+
+	server.Flush(t, notifier)
+	if routes := server.Routes(); len(routes) != 0 {
+		t.Fatalf("got route stats %v, wanted none", routes)
+	}
+	queries := server.Queries()
+	if len(queries) != 1 || queries[0].Query != "pipeline" || queries[0].Route != "/orders" {
+		t.Fatalf("got queries %+v", queries)
+	}
+}
+
+func TestHookWithoutNotifier(t *testing.T) {
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/")
+	process := NewHook(nil).ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return nil
+	})
+	if err := process(ctx, redis.NewStringCmd(ctx, "get", "key")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metric.Groups()["redis"]; !ok {
+		t.Error("redis group is not recorded")
+	}
+}
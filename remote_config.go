@@ -0,0 +1,248 @@
+package gobrake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultHost = "https://api.airbrake.io"
+const defaultRemoteConfigHost = "https://notifier-configs.airbrake.io"
+
+const (
+	defaultRemoteConfigInterval = 10 * time.Minute
+	minRemoteConfigInterval     = 30 * time.Second
+)
+
+var errErrorsDisabledRemotely = errors.New(
+	"gobrake: error notifications are disabled by remote config")
+
+type remoteSetting struct {
+	Name     string  `json:"name"`
+	Enabled  bool    `json:"enabled"`
+	Endpoint *string `json:"endpoint"`
+}
+
+type remoteConfigJSON struct {
+	ProjectId   int64           `json:"project_id"`
+	UpdatedAt   int64           `json:"updated_at"`
+	PollSec     int64           `json:"poll_sec"`
+	ConfigRoute string          `json:"config_route"`
+	Settings    []remoteSetting `json:"settings"`
+}
+
+// remoteConfig periodically fetches notifier config that can disable
+// error notifications or APM and change API hosts without redeploying.
+// Methods are safe to call on nil config which means that remote config
+// is disabled.
+type remoteConfig struct {
+	opt *NotifierOptions
+	url string
+
+	mu             sync.RWMutex
+	errorsDisabled bool
+	apmDisabled    bool
+	errorHost      string
+	apmHost        string
+	interval       time.Duration
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newRemoteConfig(opt *NotifierOptions) *remoteConfig {
+	c := &remoteConfig{
+		opt: opt,
+		url: fmt.Sprintf("%s/2020-06-18/config/%d/config.json",
+			opt.RemoteConfigHost, opt.ProjectId),
+		interval: defaultRemoteConfigInterval,
+		done:     make(chan struct{}),
+	}
+	c.loadCache()
+	return c
+}
+
+// Start fetches config in the background until Stop is called.
+func (c *remoteConfig) Start() {
+	if c == nil {
+		return
+	}
+	go c.poll()
+}
+
+func (c *remoteConfig) Stop() {
+	if c == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *remoteConfig) poll() {
+	for {
+		err := c.fetch()
+		if err != nil {
+			c.opt.logger().Printf("remoteConfig.fetch failed: %s", err)
+		}
+
+		c.mu.RLock()
+		interval := c.interval
+		c.mu.RUnlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *remoteConfig) fetch() error {
+	resp, err := c.opt.HTTPClient.Get(c.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got unexpected response status=%q", resp.Status)
+	}
+
+	var cfg remoteConfigJSON
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return err
+	}
+
+	c.apply(&cfg)
+	c.saveCache(b)
+	return nil
+}
+
+func (c *remoteConfig) apply(cfg *remoteConfigJSON) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cfg.PollSec > 0 {
+		c.interval = time.Duration(cfg.PollSec) * time.Second
+		if c.interval < minRemoteConfigInterval {
+			c.interval = minRemoteConfigInterval
+		}
+	}
+
+	for _, s := range cfg.Settings {
+		endpoint := c.endpoint(&s)
+
+		switch s.Name {
+		case "errors":
+			c.errorsDisabled = !s.Enabled
+			c.errorHost = endpoint
+		case "apm":
+			c.apmDisabled = !s.Enabled
+			c.apmHost = endpoint
+		}
+	}
+}
+
+// endpoint returns the normalized endpoint of the setting. Invalid and
+// plain http endpoints that are not allowed by AllowInsecureHost are
+// ignored, so the configured host is used instead.
+func (c *remoteConfig) endpoint(s *remoteSetting) string {
+	if s.Endpoint == nil || strings.TrimSpace(*s.Endpoint) == "" {
+		return ""
+	}
+	host, err := normalizeHost(*s.Endpoint, c.opt.AllowInsecureHost)
+	if err != nil {
+		c.opt.logger().Printf("remoteConfig.apply setting=%q failed: %s", s.Name, err)
+		return ""
+	}
+	return host
+}
+
+// loadCache applies the config saved by a previous process so remote
+// settings take effect before the first fetch completes.
+func (c *remoteConfig) loadCache() {
+	if c.opt.RemoteConfigCacheFile == "" {
+		return
+	}
+
+	b, err := ioutil.ReadFile(c.opt.RemoteConfigCacheFile)
+	if err != nil {
+		return
+	}
+
+	var cfg remoteConfigJSON
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return
+	}
+	c.apply(&cfg)
+}
+
+func (c *remoteConfig) saveCache(b []byte) {
+	if c.opt.RemoteConfigCacheFile == "" {
+		return
+	}
+
+	err := ioutil.WriteFile(c.opt.RemoteConfigCacheFile, b, 0600)
+	if err != nil {
+		c.opt.logger().Printf("remoteConfig.saveCache file=%q failed: %s",
+			c.opt.RemoteConfigCacheFile, err)
+	}
+}
+
+func (c *remoteConfig) ErrorsEnabled() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.errorsDisabled
+}
+
+func (c *remoteConfig) APMEnabled() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.apmDisabled
+}
+
+// ErrorHost returns the host notices are sent to.
+func (c *remoteConfig) ErrorHost(opt *NotifierOptions) string {
+	if c == nil {
+		return opt.Host
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.errorHost != "" {
+		return c.errorHost
+	}
+	return opt.Host
+}
+
+// APMHost returns the host requests stats are sent to.
+func (c *remoteConfig) APMHost(opt *NotifierOptions) string {
+	if c == nil {
+		return opt.Host
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.apmHost != "" {
+		return c.apmHost
+	}
+	return opt.Host
+}
@@ -0,0 +1,66 @@
+package gobrake_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("remote config", func() {
+	var notifier *gobrake.Notifier
+	var config string
+	var cacheFile string
+	var noticeRequests int
+
+	BeforeEach(func() {
+		noticeRequests = 0
+		config = `{"poll_sec": 600, "settings": [{"name": "errors", "enabled": false}]}`
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/2020-06-18/config/1/config.json":
+				w.Write([]byte(config))
+			default:
+				noticeRequests++
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id":"123"}`))
+			}
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		dir, err := ioutil.TempDir("", "gobrake")
+		Expect(err).NotTo(HaveOccurred())
+		cacheFile = filepath.Join(dir, "config.json")
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:             1,
+			ProjectKey:            "key",
+			Host:                  server.URL,
+			RemoteConfigHost:      server.URL,
+			RemoteConfigCacheFile: cacheFile,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		os.RemoveAll(filepath.Dir(cacheFile))
+	})
+
+	It("disables error notifications and caches the config", func() {
+		Eventually(func() (string, error) {
+			b, err := ioutil.ReadFile(cacheFile)
+			return string(b), err
+		}).Should(Equal(config))
+
+		notice := notifier.Notice("hello", nil, 0)
+		_, err := notifier.SendNotice(notice)
+		Expect(err).To(MatchError("gobrake: error notifications are disabled by remote config"))
+		Expect(noticeRequests).To(Equal(0))
+	})
+})
@@ -0,0 +1,74 @@
+package gobrake
+
+import (
+	"strings"
+)
+
+// RequestDetails describes the request an error happened in for servers
+// that do not use net/http, e.g. fasthttp, so adapters don't have to
+// allocate an http.Request.
+type RequestDetails struct {
+	Method     string
+	URL        string
+	RemoteAddr string
+	// VisitHeaders calls fn for every request header value.
+	VisitHeaders func(fn func(key, value string))
+}
+
+// SetRequestDetails is like SetRequestHeaders, but fills in the notice
+// from RequestDetails.
+func (n *Notice) SetRequestDetails(req *RequestDetails, allow, deny []string) {
+	n.Context["url"] = req.URL
+	n.Context["httpMethod"] = req.Method
+
+	var forwardedFor, realIP string
+	if req.VisitHeaders != nil {
+		req.VisitHeaders(func(k, v string) {
+			switch {
+			case strings.EqualFold(k, "User-Agent"):
+				n.Context["userAgent"] = v
+			case strings.EqualFold(k, "Referer"):
+				n.Context["referer"] = v
+			case strings.EqualFold(k, "X-Forwarded-For"):
+				forwardedFor = v
+			case strings.EqualFold(k, "X-Real-Ip"):
+				realIP = v
+			}
+
+			if isDeniedHeader(deny, k) {
+				return
+			}
+			if len(allow) > 0 && !containsHeader(allow, k) {
+				return
+			}
+
+			switch prev := n.Env[k].(type) {
+			case nil:
+				n.Env[k] = v
+			case string:
+				n.Env[k] = []string{prev, v}
+			case []string:
+				n.Env[k] = append(prev, v)
+			}
+		})
+	}
+
+	var addr string
+	switch {
+	case forwardedFor != "":
+		addr = normalizeIP(strings.TrimSpace(strings.Split(forwardedFor, ",")[0]))
+	case realIP != "":
+		addr = normalizeIP(realIP)
+	default:
+		addr = hostIP(req.RemoteAddr)
+	}
+	if addr != "" {
+		n.Context["userAddr"] = addr
+	}
+}
+
+// SetNoticeRequest fills in the notice from RequestDetails using notifier
+// HeadersAllowlist and HeadersDenylist merged with DefaultDeniedHeaders.
+func (n *Notifier) SetNoticeRequest(notice *Notice, req *RequestDetails) {
+	notice.SetRequestDetails(req, n.opt.HeadersAllowlist, n.opt.HeadersDenylist)
+}
@@ -0,0 +1,75 @@
+package gobrake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrInvalidProjectKey is returned when Airbrake rejects the project
+	// id or key.
+	ErrInvalidProjectKey = errors.New("gobrake: unauthorized: invalid project id or key")
+	// ErrPayloadTooLarge is returned when Airbrake rejects the payload
+	// because of its size.
+	ErrPayloadTooLarge = errors.New("gobrake: payload is too large")
+)
+
+// ResponseError is returned when Airbrake API responds with an error
+// status. Message is the error reported in the response body. Err is
+// ErrInvalidProjectKey, ErrPayloadTooLarge or nil for other errors.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Message    string
+	Err        error
+}
+
+func (e *ResponseError) Error() string {
+	var s string
+	if e.Err != nil {
+		s = e.Err.Error()
+	} else {
+		s = fmt.Sprintf("got unexpected response status=%q", e.Status)
+	}
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	return s
+}
+
+// Unwrap returns Err, so errors.Is(err, ErrInvalidProjectKey) works.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+type responseErrorJSON struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// newResponseError returns the error described by the response and its
+// body.
+func newResponseError(resp *http.Response, body []byte) *ResponseError {
+	e := &ResponseError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+
+	var v responseErrorJSON
+	if json.Unmarshal(body, &v) == nil {
+		e.Message = v.Message
+		if e.Message == "" {
+			e.Message = v.Error
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		e.Err = ErrInvalidProjectKey
+	case http.StatusRequestEntityTooLarge:
+		e.Err = ErrPayloadTooLarge
+	}
+	return e
+}
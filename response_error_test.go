@@ -0,0 +1,69 @@
+package gobrake_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResponseError", func() {
+	var notifier *gobrake.Notifier
+	var status int32
+	var body atomic.Value
+
+	BeforeEach(func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			w.Write([]byte(body.Load().(string)))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	send := func(code int, respBody string) *gobrake.ResponseError {
+		atomic.StoreInt32(&status, int32(code))
+		body.Store(respBody)
+
+		_, err := notifier.SendNotice(gobrake.NewNotice("hello", nil, 0))
+		Expect(err).To(HaveOccurred())
+		respErr, ok := err.(*gobrake.ResponseError)
+		Expect(ok).To(BeTrue())
+		return respErr
+	}
+
+	It("returns typed errors with the API message", func() {
+		err := send(http.StatusUnauthorized, `{"message":"Project API key is invalid"}`)
+		Expect(err.Err).To(Equal(gobrake.ErrInvalidProjectKey))
+		Expect(err.Error()).To(Equal(
+			"gobrake: unauthorized: invalid project id or key: Project API key is invalid"))
+
+		err = send(http.StatusRequestEntityTooLarge, `{"error":"payload is too big"}`)
+		Expect(err.Err).To(Equal(gobrake.ErrPayloadTooLarge))
+		Expect(err.Unwrap()).To(Equal(gobrake.ErrPayloadTooLarge))
+		Expect(err.Message).To(Equal("payload is too big"))
+	})
+
+	It("keeps status of unknown errors", func() {
+		err := send(http.StatusBadRequest, `{"message":"invalid JSON"}`)
+		Expect(err.Err).To(BeNil())
+		Expect(err.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(err.Error()).To(Equal(`got unexpected response status="400 Bad Request": invalid JSON`))
+
+		err = send(http.StatusBadGateway, `<html>`)
+		Expect(err.Error()).To(Equal(`got unexpected response status="502 Bad Gateway"`))
+	})
+})
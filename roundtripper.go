@@ -0,0 +1,51 @@
+package gobrake
+
+import (
+	"net/http"
+	"time"
+)
+
+// RoundTripper times outbound HTTP requests and adds their durations to
+// the "http" group of the route metric carried by the request context.
+// The duration is measured until response headers are received.
+type RoundTripper struct {
+	base     http.RoundTripper
+	notifier *Notifier
+}
+
+// NewRoundTripper wraps the base round tripper. http.DefaultTransport is
+// used when base is nil.
+func NewRoundTripper(base http.RoundTripper) *RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RoundTripper{
+		base: base,
+	}
+}
+
+// WithHostStats returns a copy of the round tripper that also aggregates
+// latencies of external hosts, e.g. api.example.com. They are kept apart
+// from requests stats and reported by Notifier.HostStats and
+// NotifierStats.OutboundHosts.
+func (rt *RoundTripper) WithHostStats(notifier *Notifier) *RoundTripper {
+	cp := *rt
+	cp.notifier = notifier
+	return &cp
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.base.RoundTrip(req)
+	end := time.Now()
+
+	ContextRouteMetric(req.Context()).AddGroup("http", end.Sub(start))
+
+	if rt.notifier != nil {
+		failed := err != nil || resp.StatusCode >= 500
+		rt.notifier.hosts.Add(req.URL.Host, end.Sub(start), failed)
+	}
+
+	return resp, err
+}
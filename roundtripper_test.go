@@ -0,0 +1,82 @@
+package gobrake_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoundTripper", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("adds outbound requests duration to http group", func() {
+		ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+		client := &http.Client{
+			Transport: gobrake.NewRoundTripper(nil),
+		}
+
+		req, err := http.NewRequest("GET", server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Do(req.WithContext(ctx))
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(metric.Groups()["http"]).To(BeNumerically(">=", 5*time.Millisecond))
+	})
+
+	It("aggregates external host latencies apart from requests stats", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost:1",
+		})
+		defer notifier.Close()
+
+		var reqs []*gobrake.RequestInfo
+		notifier.AddRequestFilter(func(req *gobrake.RequestInfo) *gobrake.RequestInfo {
+			reqs = append(reqs, req)
+			return nil
+		})
+
+		client := &http.Client{
+			Transport: gobrake.NewRoundTripper(nil).WithHostStats(notifier),
+		}
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+		}
+		_, err := client.Get("http://localhost:1")
+		Expect(err).To(HaveOccurred())
+
+		Expect(reqs).To(BeEmpty())
+		hosts := notifier.HostStats()
+		Expect(hosts).To(HaveLen(2))
+
+		stat := hosts[server.Listener.Addr().String()]
+		Expect(stat.Count).To(Equal(2))
+		Expect(stat.Errors).To(Equal(0))
+		Expect(stat.Sum).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(stat.Max).To(BeNumerically(">=", 5*time.Millisecond))
+		Expect(hosts["localhost:1"].Errors).To(Equal(1))
+		Expect(notifier.Stats().OutboundHosts).To(Equal(hosts))
+	})
+})
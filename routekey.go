@@ -0,0 +1,155 @@
+package gobrake
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routeKey identifies stats of a route in a minute bucket. It is used as
+// a map key, so it is kept small: the bucket is stored as unix minute and
+// strings of stored keys are interned.
+type routeKey struct {
+	Method         string
+	Route          string
+	StatusCode     int
+	ClientIdentity string
+	Minute         int64 // unix time in minutes
+}
+
+// routeKeyJSON is the routeKey representation used by Airbrake API.
+type routeKeyJSON struct {
+	Method         string    `json:"method"`
+	Route          string    `json:"route"`
+	StatusCode     int       `json:"statusCode"`
+	ClientIdentity string    `json:"clientIdentity,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// newRouteKey returns the key of the minute bucket the request belongs
+// to. Requests are bucketed by end time, so a long request is attributed
+// to the minute it completed in and can not end up in a bucket that was
+// flushed while the request was still in flight.
+func newRouteKey(req *RequestInfo) routeKey {
+	return routeKey{
+		Method:         req.Method,
+		Route:          req.Route,
+		StatusCode:     req.StatusCode,
+		ClientIdentity: req.ClientIdentity,
+		Minute:         unixMinute(req.End),
+	}
+}
+
+// routeKey is like newRouteKey, but applies StatusCodeClasses and
+// ClockSkewCorrection. The request is not modified, so it can be shared
+// by concurrent callers.
+func (opt *NotifierOptions) routeKey(req *RequestInfo) routeKey {
+	key := newRouteKey(req)
+	if opt.clock != nil {
+		key.Minute = unixMinute(opt.clock.Bucket(req.End))
+	}
+	if opt.StatusCodeClasses {
+		key.StatusCode = statusCodeClass(key.StatusCode)
+	}
+	return key
+}
+
+// unixMinute returns the number of minutes elapsed since the Unix epoch
+// rounded down.
+func unixMinute(tm time.Time) int64 {
+	sec := tm.Unix()
+	if sec < 0 {
+		sec -= 59
+	}
+	return sec / 60
+}
+
+// Time returns the start of the minute bucket in UTC.
+func (k routeKey) Time() time.Time {
+	return time.Unix(k.Minute*60, 0).UTC()
+}
+
+func (k routeKey) json() routeKeyJSON {
+	return routeKeyJSON{
+		Method:         k.Method,
+		Route:          k.Route,
+		StatusCode:     k.StatusCode,
+		ClientIdentity: k.ClientIdentity,
+		Time:           k.Time(),
+	}
+}
+
+// String returns a stable representation of the key, e.g.
+// "GET /hello 200 2018-01-01T00:01:00Z". Client identity is included
+// only when it is set.
+func (k routeKey) String() string {
+	parts := []string{k.Method, k.Route, strconv.Itoa(k.StatusCode)}
+	if k.ClientIdentity != "" {
+		parts = append(parts, k.ClientIdentity)
+	}
+	parts = append(parts, k.Time().Format(time.RFC3339))
+	return strings.Join(parts, " ")
+}
+
+// intern returns the key with interned strings. It is called only when a
+// key is stored, so lookups do not pay for it.
+func (k routeKey) intern() routeKey {
+	k.Method = routeStrings.Intern(k.Method)
+	k.Route = routeStrings.Intern(k.Route)
+	k.ClientIdentity = routeStrings.Intern(k.ClientIdentity)
+	return k
+}
+
+// statusCodeClass returns the first code of the status code class, e.g.
+// 200 for 204 and 400 for 404. Invalid codes are returned as is.
+func statusCodeClass(code int) int {
+	if code < 100 || code > 599 {
+		return code
+	}
+	return code / 100 * 100
+}
+
+const maxInternedStrings = 10000
+
+// routeStrings interns methods, routes and client identities of stored
+// keys, so stats of a route in different flush windows share a single
+// string instead of retaining the one of the first request.
+var routeStrings = newInterner(maxInternedStrings)
+
+// interner deduplicates strings. Once limit strings are interned, new
+// strings are returned as is, so unbounded routes can not grow it.
+type interner struct {
+	limit int
+
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newInterner(limit int) *interner {
+	return &interner{
+		limit: limit,
+		m:     make(map[string]string),
+	}
+}
+
+func (in *interner) Intern(s string) string {
+	if s == "" {
+		return s
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if interned, ok := in.m[s]; ok {
+		return interned
+	}
+	if len(in.m) >= in.limit {
+		return s
+	}
+	// s may be a substring of a much larger string, e.g. the request URL,
+	// so a copy is stored to not retain the larger one.
+	c := string([]byte(s))
+	in.m[c] = c
+	return c
+}
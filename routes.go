@@ -2,10 +2,14 @@ package gobrake
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tdigest "github.com/caio/go-tdigest"
@@ -19,135 +23,460 @@ type RequestInfo struct {
 	StatusCode int
 	Start      time.Time
 	End        time.Time
+	// Identity of the caller, e.g. ClientCertIdentity. It is used as a
+	// stats dimension when NotifierOptions.ReportClientCertIdentity is set.
+	ClientIdentity string
 }
 
-type routeKey struct {
-	Method     string    `json:"method"`
-	Route      string    `json:"route"`
-	StatusCode int       `json:"statusCode"`
-	Time       time.Time `json:"time"`
+type routeStat struct {
+	mu             sync.Mutex
+	Count          int             `json:"count"`
+	Sum            float64         `json:"sum"`
+	Sumsq          float64         `json:"sumsq"`
+	TDigest        []byte          `json:"tdigest,omitempty"`
+	TDigestVersion int             `json:"tdigestVersion,omitempty"`
+	Histogram      *routeHistogram `json:"histogram,omitempty"`
+
+	compression uint32
+	td          *tdigest.TDigest
+	hist        *expHistogram
 }
 
-type routeStat struct {
-	mu      sync.Mutex
-	Count   int     `json:"count"`
-	Sum     float64 `json:"sum"`
-	Sumsq   float64 `json:"sumsq"`
-	TDigest []byte  `json:"tdigest"`
-	td      *tdigest.TDigest
+func newRouteStat(opt *NotifierOptions) *routeStat {
+	s := &routeStat{
+		compression: opt.TDigestCompression,
+	}
+	if len(opt.StatsHistogramBuckets) > 0 {
+		s.Histogram = newRouteHistogram(opt.StatsHistogramBuckets)
+	}
+	return s
 }
 
 func (s *routeStat) Add(ms float64) error {
+	s.Count++
+	s.Sum += ms
+	s.Sumsq += ms * ms
+	if s.hist != nil {
+		s.hist.Add(ms)
+	}
+
+	if s.Histogram != nil {
+		s.Histogram.Add(ms)
+		return nil
+	}
+
 	if s.td == nil {
-		td, err := tdigest.New(tdigest.Compression(20))
+		compression := s.compression
+		if compression == 0 {
+			compression = defaultTDigestCompression
+		}
+		td, err := tdigest.New(tdigest.Compression(compression))
 		if err != nil {
 			return err
 		}
 		s.td = td
 	}
-
-	s.Count++
-	s.Sum += ms
-	s.Sumsq += ms * ms
 	return s.td.Add(ms)
 }
 
+// compress compresses the t-digest and stores its serialized form.
+func (s *routeStat) compress() error {
+	if s.td == nil {
+		return nil
+	}
+
+	err := s.td.Compress()
+	if err != nil {
+		return err
+	}
+
+	b, err := s.td.AsBytes()
+	if err != nil {
+		return err
+	}
+	s.TDigest = b
+	s.TDigestVersion = TDigestVersion
+	return nil
+}
+
+// diagnose adds the compressed t-digest to the flush diagnostics.
+func (s *routeStat) diagnose(d *TDigestStats) {
+	if s.td != nil {
+		d.add(s.td, s.TDigest)
+	}
+}
+
+// routeHistogram counts durations into fixed buckets. Counts has one more
+// element than Bounds for durations above the last bound.
+type routeHistogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []int     `json:"counts"`
+}
+
+func newRouteHistogram(bounds []float64) *routeHistogram {
+	return &routeHistogram{
+		Bounds: bounds,
+		Counts: make([]int, len(bounds)+1),
+	}
+}
+
+// histogramBounds returns a sorted copy of the bounds without duplicates
+// and NaNs, so the caller can not change bounds used by stats.
+func histogramBounds(bounds []float64) []float64 {
+	sorted := make([]float64, 0, len(bounds))
+	for _, b := range bounds {
+		if !math.IsNaN(b) {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Float64s(sorted)
+
+	uniq := sorted[:0]
+	for _, b := range sorted {
+		if len(uniq) == 0 || b != uniq[len(uniq)-1] {
+			uniq = append(uniq, b)
+		}
+	}
+	return uniq
+}
+
+func (h *routeHistogram) Add(ms float64) {
+	i := sort.SearchFloat64s(h.Bounds, ms)
+	h.Counts[i]++
+}
+
 type routeKeyStat struct {
-	routeKey
+	routeKeyJSON
 	*routeStat
 }
 
 // routeStats aggregates information about requests and periodically sends
 // collected data to Airbrake.
 type routeStats struct {
-	opt    *NotifierOptions
-	apiURL string
+	opt          *NotifierOptions
+	remoteConfig *remoteConfig
+	breaker      *circuitBreaker
+	metrics      *notifierMetrics
+	otlp         *otlpExporter
+	statsd       *statsdMirror
+	unsupported  unsupportedStream
+
+	// mu is read-locked while a sample is added and write-locked when the
+	// flush window is swapped, so samples are never added to stats that
+	// are being sent.
+	mu      sync.RWMutex
+	m       *routeStatsWindow
+	dropped routesStatsDropped
 
+	flushTimer *time.Timer
+}
+
+// routeStatsShards is the number of shards of a flush window.
+const routeStatsShards = 32
+
+type routeStatsShard struct {
 	mu sync.Mutex
 	m  map[routeKey]*routeStat
+}
 
-	flushTimer *time.Timer
+// routeStatsWindow holds stats collected during a flush window. Stats are
+// split into shards by route, so concurrent requests to different routes
+// do not contend on a single lock.
+type routeStatsWindow struct {
+	shards [routeStatsShards]routeStatsShard
 }
 
-func newRouteStats(opt *NotifierOptions) *routeStats {
-	return &routeStats{
-		opt: opt,
-		apiURL: fmt.Sprintf("%s/api/v5/projects/%d/routes-stats",
-			opt.Host, opt.ProjectId),
+// stat returns the stat of the key, creating it if needed.
+func (w *routeStatsWindow) stat(opt *NotifierOptions, key *routeKey, hist bool) *routeStat {
+	shard := &w.shards[key.shard()]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if shard.m == nil {
+		shard.m = make(map[routeKey]*routeStat)
+	}
+	stat, ok := shard.m[*key]
+	if !ok {
+		stat = newRouteStat(opt)
+		if hist {
+			stat.hist = newExpHistogram()
+		}
+		shard.m[key.intern()] = stat
+	}
+	return stat
+}
+
+// merge returns stats of all shards. It is safe to call on nil window.
+func (w *routeStatsWindow) merge() map[routeKey]*routeStat {
+	if w == nil {
+		return nil
+	}
+
+	var n int
+	for i := range w.shards {
+		n += len(w.shards[i].m)
+	}
+	m := make(map[routeKey]*routeStat, n)
+	for i := range w.shards {
+		shard := &w.shards[i]
+		shard.mu.Lock()
+		for k, v := range shard.m {
+			m[k] = v
+		}
+		shard.mu.Unlock()
+	}
+	return m
+}
+
+// shard returns the shard index of the key using FNV-1a hash of the
+// method, route and status code. Bucket time is not hashed, because all
+// keys of a window usually share it.
+func (k *routeKey) shard() int {
+	const prime = 16777619
+	h := uint32(2166136261)
+	for i := 0; i < len(k.Method); i++ {
+		h = (h ^ uint32(k.Method[i])) * prime
+	}
+	for i := 0; i < len(k.Route); i++ {
+		h = (h ^ uint32(k.Route[i])) * prime
+	}
+	h = (h ^ uint32(k.StatusCode)) * prime
+	return int(h % routeStatsShards)
+}
+
+func newRouteStats(opt *NotifierOptions, rc *remoteConfig) *routeStats {
+	s := &routeStats{
+		opt:          opt,
+		remoteConfig: rc,
+		unsupported:  unsupportedStream{name: "routeStats"},
+	}
+	if opt.OTLPMetricsURL != "" {
+		s.otlp = newOTLPExporter(opt)
+	}
+	if opt.StatsdAddr != "" {
+		statsd, err := newStatsdMirror(opt)
+		if err != nil {
+			opt.logger().Printf("newStatsdMirror addr=%q failed: %s", opt.StatsdAddr, err)
+		} else {
+			s.statsd = statsd
+		}
+	}
+	return s
+}
+
+// Close releases the StatsD connection. Stats collected after Close are
+// still sent to Airbrake.
+func (s *routeStats) Close() {
+	if s.statsd != nil {
+		s.statsd.Close()
 	}
 }
 
 func (s *routeStats) init() {
 	if s.m == nil && s.flushTimer == nil {
-		s.m = make(map[routeKey]*routeStat)
+		s.m = new(routeStatsWindow)
 		s.flushTimer = time.AfterFunc(flushPeriod, s.flush)
 	}
 }
 
+// rlockWindow read-locks s.mu and returns the current flush window,
+// starting a new one if needed. The caller must call s.mu.RUnlock.
+func (s *routeStats) rlockWindow() *routeStatsWindow {
+	s.mu.RLock()
+	for s.m == nil {
+		s.mu.RUnlock()
+		s.mu.Lock()
+		s.init()
+		s.mu.Unlock()
+		s.mu.RLock()
+	}
+	return s.m
+}
+
 func (s *routeStats) flush() {
+	m, dropped := s.swap()
+	s.sendAll(context.Background(), m, dropped)
+}
+
+// swap returns collected stats and resets the flush window.
+func (s *routeStats) swap() (map[routeKey]*routeStat, routesStatsDropped) {
 	s.mu.Lock()
 
-	m := s.m
+	w := s.m
+	dropped := s.dropped
 	s.m = nil
-	s.flushTimer = nil
+	s.dropped = routesStatsDropped{}
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
 
 	s.mu.Unlock()
 
-	err := s.send(m)
+	return w.merge(), dropped
+}
+
+func (s *routeStats) sendAll(
+	ctx context.Context, m map[routeKey]*routeStat, dropped routesStatsDropped,
+) {
+	if len(m) == 0 && dropped == (routesStatsDropped{}) {
+		return
+	}
+
+	if !s.unsupported.Disabled() {
+		start := time.Now()
+		err := s.send(ctx, m, dropped)
+		took := time.Since(start)
+		s.metrics.flush(err, took)
+		if err != nil && !s.unsupported.Check(s.opt, err) {
+			s.opt.logger().Printf("routeStats.send failed: %s", err)
+		}
+		if s.opt.LogFlushSummary {
+			s.opt.logger().Printf("%s", flushSummary(m, took, err))
+		}
+	}
+
+	if s.otlp != nil {
+		err := s.otlp.send(ctx, m)
+		if err != nil {
+			s.opt.logger().Printf("otlpExporter.send failed: %s", err)
+		}
+	}
+}
+
+// flushSummary returns a line describing the flushed window: number of
+// routes and requests, top-3 slowest routes by mean duration and the
+// send result.
+func flushSummary(m map[routeKey]*routeStat, took time.Duration, err error) string {
+	type routeMean struct {
+		key  routeKey
+		mean float64
+	}
+
+	var requests int
+	slowest := make([]routeMean, 0, len(m))
+	for k, v := range m {
+		v.mu.Lock()
+		count, sum := v.Count, v.Sum
+		v.mu.Unlock()
+
+		requests += count
+		if count > 0 {
+			slowest = append(slowest, routeMean{key: k, mean: sum / float64(count)})
+		}
+	}
+	sort.Slice(slowest, func(i, j int) bool {
+		return slowest[i].mean > slowest[j].mean
+	})
+	if len(slowest) > 3 {
+		slowest = slowest[:3]
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "routeStats flushed routes=%d requests=%d slowest=[", len(m), requests)
+	for i, r := range slowest {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%s %s %d: %.2fms", r.key.Method, r.key.Route, r.key.StatusCode, r.mean)
+	}
+	fmt.Fprintf(buf, "] send=%s", took)
 	if err != nil {
-		logger.Printf("routeStats.send failed: %s", err)
+		fmt.Fprintf(buf, " result=%q", err)
+	} else {
+		buf.WriteString(" result=ok")
 	}
+	return buf.String()
+}
+
+// routesStatsDropped counts samples that were dropped during the flush
+// window and are not reflected in the reported stats.
+type routesStatsDropped struct {
+	InvalidDuration int `json:"invalidDuration"`
+	Expired         int `json:"expired"`
+}
+
+type routesStatsMeta struct {
+	Dropped routesStatsDropped `json:"dropped"`
 }
 
 type routesStatsJSONRequest struct {
-	Routes []routeKeyStat `json:"routes"`
+	Routes []routeKeyStat   `json:"routes"`
+	Meta   *routesStatsMeta `json:"meta,omitempty"`
 }
 
-func (s *routeStats) send(m map[routeKey]*routeStat) error {
+func (s *routeStats) send(
+	ctx context.Context, m map[routeKey]*routeStat, dropped routesStatsDropped,
+) error {
 	var routes []routeKeyStat
+	var digests TDigestStats
 	for k, v := range m {
-		err := v.td.Compress()
-		if err != nil {
-			return err
+		if s.opt.statsExpired(k.Time()) {
+			dropped.Expired += v.Count
+			continue
 		}
 
-		b, err := v.td.AsBytes()
+		err := v.compress()
 		if err != nil {
 			return err
 		}
-		v.TDigest = b
+		v.diagnose(&digests)
 
 		routes = append(routes, routeKeyStat{
-			routeKey:  k,
-			routeStat: v,
+			routeKeyJSON: k.json(),
+			routeStat:    v,
 		})
 	}
 
+	s.metrics.routesTDigest(digests)
+
 	jsonReq := routesStatsJSONRequest{
 		Routes: routes,
 	}
+	if dropped != (routesStatsDropped{}) {
+		jsonReq.Meta = &routesStatsMeta{
+			Dropped: dropped,
+		}
+	}
 
+	apiURL := fmt.Sprintf("%s/api/v5/projects/%d/routes-stats",
+		s.remoteConfig.APMHost(s.opt), s.opt.ProjectId)
+	return sendStats(ctx, s.opt, s.breaker, apiURL, jsonReq)
+}
+
+// sendStats sends JSON encoded stats to Airbrake API.
+func sendStats(ctx context.Context, opt *NotifierOptions, breaker *circuitBreaker,
+	url string, v interface{}) error {
 	buf := buffers.Get().(*bytes.Buffer)
 	defer buffers.Put(buf)
 
 	buf.Reset()
-	err := json.NewEncoder(buf).Encode(jsonReq)
+	err := json.NewEncoder(buf).Encode(v)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", s.apiURL, buf)
+	req, err := http.NewRequest("PUT", url, buf)
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
+	req, trace := withSendTrace(req)
 
-	req.Header.Set("Authorization", "Bearer "+s.opt.ProjectKey)
+	req.Header.Set("Authorization", "Bearer "+opt.ProjectKey)
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := s.opt.HTTPClient.Do(req)
+	opt.signRequest(req, buf.Bytes())
+	if !breaker.Allow() {
+		return errBreakerOpen
+	}
+	resp, err := opt.HTTPClient.Do(req)
+	breaker.Done(ctx, resp, err)
 	if err != nil {
-		return err
+		return &sendError{err: err, trace: trace}
 	}
 	defer resp.Body.Close()
+	opt.clock.Sync(resp)
 
 	buf.Reset()
 	_, err = buf.ReadFrom(resp.Body)
@@ -160,33 +489,62 @@ func (s *routeStats) send(m map[routeKey]*routeStat) error {
 	}
 
 	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return errUnauthorized
+	case http.StatusNotFound, http.StatusGone:
+		return errUnsupportedAPI
 	}
 
-	err = fmt.Errorf("got unexpected response status=%q", resp.Status)
-	return err
+	return newResponseError(resp, buf.Bytes())
+}
+
+// unsupportedStream disables a stats stream for the rest of the process
+// once the host reports that its endpoint does not exist, e.g. on
+// self-hosted Errbit.
+type unsupportedStream struct {
+	name     string
+	disabled uint32 // atomic
+}
+
+func (s *unsupportedStream) Disabled() bool {
+	return atomic.LoadUint32(&s.disabled) == 1
+}
+
+// Check disables the stream when err is errUnsupportedAPI and reports
+// whether it did so.
+func (s *unsupportedStream) Check(opt *NotifierOptions, err error) bool {
+	if err != errUnsupportedAPI {
+		return false
+	}
+	if atomic.CompareAndSwapUint32(&s.disabled, 0, 1) {
+		opt.logger().Printf("%s: API endpoint is not supported by the host; "+
+			"disabling it for the process", s.name)
+	}
+	return true
 }
 
 func (s *routeStats) NotifyRequest(req *RequestInfo) error {
-	key := routeKey{
-		Method:     req.Method,
-		Route:      req.Route,
-		StatusCode: req.StatusCode,
-		Time:       req.Start.UTC().Truncate(time.Minute),
+	if s.unsupported.Disabled() && s.otlp == nil && s.statsd == nil {
+		return nil
 	}
 
-	s.mu.Lock()
-	s.init()
-	stat, ok := s.m[key]
-	if !ok {
-		stat = &routeStat{}
-		s.m[key] = stat
+	if req.Start.IsZero() || req.End.Before(req.Start) {
+		s.mu.Lock()
+		s.init()
+		s.dropped.InvalidDuration++
+		s.mu.Unlock()
+		return nil
 	}
-	s.mu.Unlock()
 
+	key := s.opt.routeKey(req)
 	ms := float64(req.End.Sub(req.Start)) / float64(time.Millisecond)
 
+	if s.statsd != nil {
+		s.statsd.Timing(&key, ms)
+	}
+
+	w := s.rlockWindow()
+	defer s.mu.RUnlock()
+
+	stat := w.stat(s.opt, &key, s.otlp != nil)
 	stat.mu.Lock()
 	err := stat.Add(ms)
 	stat.mu.Unlock()
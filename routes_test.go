@@ -0,0 +1,410 @@
+package gobrake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("routeStats", func() {
+	var routes *routeStats
+	type sentRoute struct {
+		Count int `json:"count"`
+	}
+	var sentReq *struct {
+		Routes []sentRoute      `json:"routes"`
+		Meta   *routesStatsMeta `json:"meta"`
+	}
+
+	BeforeEach(func() {
+		sentReq = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			err = json.Unmarshal(b, &sentReq)
+			if err != nil {
+				panic(err)
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		opt := &NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		}
+		opt.init()
+		routes = newRouteStats(opt, nil)
+	})
+
+	flush := func() {
+		routes.flush()
+	}
+
+	It("reports dropped samples with invalid duration", func() {
+		start := time.Now()
+
+		err := routes.NotifyRequest(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = routes.NotifyRequest(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(-time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		flush()
+
+		Expect(sentReq.Routes).To(HaveLen(1))
+		Expect(sentReq.Routes[0].Count).To(Equal(1))
+		Expect(sentReq.Meta).NotTo(BeNil())
+		Expect(sentReq.Meta.Dropped.InvalidDuration).To(Equal(1))
+	})
+
+	It("drops expired stats", func() {
+		start := time.Now().Add(-25 * time.Hour)
+
+		err := routes.NotifyRequest(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		flush()
+
+		Expect(sentReq.Routes).To(BeEmpty())
+		Expect(sentReq.Meta.Dropped.Expired).To(Equal(1))
+	})
+
+	It("omits meta when nothing is dropped", func() {
+		start := time.Now()
+
+		err := routes.NotifyRequest(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		flush()
+
+		Expect(sentReq.Routes).To(HaveLen(1))
+		Expect(sentReq.Meta).To(BeNil())
+	})
+
+	It("aggregates concurrent requests across shards", func() {
+		start := time.Now()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					err := routes.NotifyRequest(&RequestInfo{
+						Method:     "GET",
+						Route:      fmt.Sprintf("/hello/%d", j%50),
+						StatusCode: 200,
+						Start:      start,
+						End:        start.Add(time.Millisecond),
+					})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}()
+		}
+		wg.Wait()
+
+		flush()
+
+		Expect(sentReq.Routes).To(HaveLen(50))
+		var count int
+		for _, route := range sentReq.Routes {
+			count += route.Count
+		}
+		Expect(count).To(Equal(800))
+	})
+})
+
+var _ = Describe("flushSummary", func() {
+	It("lists top-3 slowest routes", func() {
+		m := map[routeKey]*routeStat{
+			{Method: "GET", Route: "/a", StatusCode: 200}:  {Count: 2, Sum: 10},
+			{Method: "GET", Route: "/b", StatusCode: 200}:  {Count: 1, Sum: 50},
+			{Method: "POST", Route: "/c", StatusCode: 201}: {Count: 4, Sum: 40},
+			{Method: "GET", Route: "/d", StatusCode: 404}:  {Count: 1, Sum: 1},
+		}
+
+		s := flushSummary(m, time.Millisecond, nil)
+		Expect(s).To(Equal("routeStats flushed routes=4 requests=8 slowest=[" +
+			"GET /b 200: 50.00ms, POST /c 201: 10.00ms, GET /a 200: 5.00ms] send=1ms result=ok"))
+	})
+})
+
+var _ = Describe("APM toggles", func() {
+	req := func() *RequestInfo {
+		start := time.Now()
+		return &RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(time.Millisecond),
+		}
+	}
+
+	It("does not aggregate requests when APM is disabled", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost",
+			DisableAPM: true,
+		})
+		defer notifier.Close()
+
+		Expect(notifier.NotifyRequest(req())).NotTo(HaveOccurred())
+		Expect(notifier.routes.m).To(BeNil())
+		Expect(notifier.routes.flushTimer).To(BeNil())
+	})
+
+	It("keeps route breakdowns when route stats are disabled", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:         1,
+			ProjectKey:        "key",
+			Host:              "http://localhost",
+			DisableRouteStats: true,
+		})
+		defer notifier.Close()
+
+		_, metric := NewRouteMetric(context.Background(), "GET", "/hello")
+		metric.End = metric.Start.Add(time.Millisecond)
+		Expect(notifier.NotifyRouteMetric(metric)).NotTo(HaveOccurred())
+		Expect(notifier.routes.m).To(BeNil())
+		Expect(notifier.breakdowns.swap()).To(HaveLen(1))
+	})
+
+	It("disables APM for Errbit hosts", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       "http://localhost",
+			Errbit:     true,
+		})
+		defer notifier.Close()
+
+		Expect(notifier.NotifyRequest(req())).NotTo(HaveOccurred())
+		Expect(notifier.routes.m).To(BeNil())
+		Expect(notifier.remoteConfig).To(BeNil())
+		Expect(notifier.CheckIn("job")).NotTo(HaveOccurred())
+	})
+
+	It("stops sending stats that the host does not support", func() {
+		var requests int
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusNotFound)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+		defer notifier.Close()
+
+		Expect(notifier.NotifyRequest(req())).NotTo(HaveOccurred())
+		notifier.routes.flush()
+		Expect(requests).To(Equal(1))
+		Expect(notifier.routes.unsupported.Disabled()).To(BeTrue())
+
+		Expect(notifier.NotifyRequest(req())).NotTo(HaveOccurred())
+		Expect(notifier.routes.m).To(BeNil())
+		notifier.routes.flush()
+		Expect(requests).To(Equal(1))
+	})
+})
+
+var _ = Describe("newRouteKey", func() {
+	It("uses client identity only when enabled", func() {
+		for _, enabled := range []bool{false, true} {
+			notifier := NewNotifierWithOptions(&NotifierOptions{
+				ProjectId:                1,
+				ProjectKey:               "key",
+				Host:                     "http://localhost",
+				ReportClientCertIdentity: enabled,
+			})
+
+			start := time.Now()
+			err := notifier.NotifyRequest(&RequestInfo{
+				Method:         "GET",
+				Route:          "/hello",
+				StatusCode:     200,
+				Start:          start,
+				End:            start.Add(time.Millisecond),
+				ClientIdentity: "api",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			m, _ := notifier.routes.swap()
+			for key := range m {
+				if enabled {
+					Expect(key.ClientIdentity).To(Equal("api"))
+				} else {
+					Expect(key.ClientIdentity).To(Equal(""))
+				}
+			}
+			Expect(m).To(HaveLen(1))
+			notifier.Close()
+		}
+	})
+
+	It("buckets requests by end time", func() {
+		start := time.Date(2018, 1, 1, 0, 0, 59, 0, time.UTC)
+		key := newRouteKey(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			Start:      start,
+			End:        start.Add(30 * time.Second),
+		})
+		Expect(key.Time()).To(Equal(time.Date(2018, 1, 1, 0, 1, 0, 0, time.UTC)))
+	})
+
+	It("buckets status codes into classes when enabled", func() {
+		opt := &NotifierOptions{StatusCodeClasses: true}
+		for code, class := range map[int]int{
+			0: 0, 200: 200, 204: 200, 301: 300, 404: 400, 503: 500, 999: 999,
+		} {
+			req := &RequestInfo{StatusCode: code}
+			Expect(opt.routeKey(req).StatusCode).To(Equal(class))
+			Expect(req.StatusCode).To(Equal(code))
+		}
+
+		opt.StatusCodeClasses = false
+		Expect(opt.routeKey(&RequestInfo{StatusCode: 204}).StatusCode).To(Equal(204))
+	})
+
+	It("has a stable serialization", func() {
+		key := newRouteKey(&RequestInfo{
+			Method:     "GET",
+			Route:      "/hello",
+			StatusCode: 200,
+			End:        time.Date(2018, 1, 1, 0, 1, 30, 0, time.UTC),
+		})
+		Expect(key.Minute).To(Equal(int64(25246081)))
+		Expect(key.String()).To(Equal("GET /hello 200 2018-01-01T00:01:00Z"))
+
+		key.ClientIdentity = "api"
+		Expect(key.String()).To(Equal("GET /hello 200 api 2018-01-01T00:01:00Z"))
+
+		b, err := json.Marshal(key.json())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"method":"GET","route":"/hello","statusCode":200,` +
+			`"clientIdentity":"api","time":"2018-01-01T00:01:00Z"}`))
+	})
+
+	It("rounds minutes before the epoch down", func() {
+		Expect(unixMinute(time.Unix(-1, 0))).To(Equal(int64(-1)))
+		Expect(unixMinute(time.Unix(-60, 0))).To(Equal(int64(-1)))
+		Expect(unixMinute(time.Unix(59, 0))).To(Equal(int64(0)))
+	})
+
+	It("interns strings up to the limit", func() {
+		in := newInterner(1)
+		a := in.Intern(string([]byte("/hello")))
+		b := in.Intern(string([]byte("/hello")))
+		Expect(b).To(Equal(a))
+		Expect(in.m).To(HaveLen(1))
+
+		Expect(in.Intern("/world")).To(Equal("/world"))
+		Expect(in.m).To(HaveLen(1))
+	})
+
+	It("interns a copy of the string", func() {
+		in := newInterner(1)
+		url := "/hello?query=" + string(make([]byte, 1024))
+		route := url[:len("/hello")]
+
+		interned := in.Intern(route)
+		Expect(interned).To(Equal("/hello"))
+		Expect(stringData(interned)).NotTo(Equal(stringData(route)))
+		Expect(in.Intern(route)).To(BeIdenticalTo(interned))
+	})
+})
+
+var _ = Describe("routeStat", func() {
+	It("uses configured t-digest compression", func() {
+		opt := &NotifierOptions{TDigestCompression: 100}
+		stat := newRouteStat(opt)
+		Expect(stat.Add(1)).NotTo(HaveOccurred())
+		Expect(stat.td.Compression()).To(Equal(float64(100)))
+	})
+
+	It("counts durations into fixed histogram buckets", func() {
+		stat := newRouteStat(&NotifierOptions{
+			StatsHistogramBuckets: []float64{10, 100},
+		})
+		for _, ms := range []float64{1, 10, 50, 500} {
+			Expect(stat.Add(ms)).NotTo(HaveOccurred())
+		}
+		Expect(stat.compress()).NotTo(HaveOccurred())
+
+		Expect(stat.td).To(BeNil())
+		Expect(stat.TDigest).To(BeNil())
+		Expect(stat.Histogram.Counts).To(Equal([]int{2, 1, 1}))
+	})
+
+	It("sorts and deduplicates histogram buckets", func() {
+		buckets := []float64{100, 10, math.NaN(), 100, 1}
+		opt := &NotifierOptions{
+			StatsHistogramBuckets: buckets,
+		}
+		opt.init()
+
+		Expect(opt.StatsHistogramBuckets).To(Equal([]float64{1, 10, 100}))
+		Expect(buckets[0]).To(Equal(100.0))
+
+		stat := newRouteStat(opt)
+		for _, ms := range []float64{0.5, 5, 50, 500} {
+			Expect(stat.Add(ms)).NotTo(HaveOccurred())
+		}
+		Expect(stat.Histogram.Counts).To(Equal([]int{1, 1, 1, 1}))
+	})
+})
+
+// stringData returns the address of the string bytes.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
@@ -0,0 +1,120 @@
+package gobrake
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+)
+
+// NoticeAction is the action taken for notices matching a NoticeRule.
+type NoticeAction string
+
+const (
+	// NoticeActionDrop drops the notice.
+	NoticeActionDrop NoticeAction = "drop"
+	// NoticeActionSample sends only SampleRate fraction of notices.
+	NoticeActionSample NoticeAction = "sample"
+	// NoticeActionRoute sends the notice to ProjectId and ProjectKey.
+	NoticeActionRoute NoticeAction = "route"
+	// NoticeActionEscalate calls Escalate and sends the notice.
+	NoticeActionEscalate NoticeAction = "escalate"
+)
+
+// NoticeRule matches notices and decides what happens to them before they
+// are sent. Empty match fields match any notice. Patterns use path.Match
+// syntax, e.g. "db/*".
+type NoticeRule struct {
+	// Severity patterns. Notices without severity have "error" severity.
+	Severity []string
+	// Component patterns.
+	Component []string
+	// Error type patterns, e.g. "*net.OpError".
+	ErrorType []string
+	// Context values, e.g. tags, the notice context must match.
+	Context map[string]string
+
+	Action NoticeAction
+	// Fraction of notices sent by NoticeActionSample, from 0 to 1.
+	SampleRate float64
+	// Project notices are sent to by NoticeActionRoute.
+	ProjectId  int64
+	ProjectKey string
+	// Hook called by NoticeActionEscalate, e.g. to page on-call.
+	Escalate func(*Notice)
+}
+
+func (r *NoticeRule) match(notice *Notice) bool {
+	severity, _ := notice.Context["severity"].(string)
+	if severity == "" {
+		severity = "error"
+	}
+	if !matchAny(r.Severity, severity) {
+		return false
+	}
+
+	component, _ := notice.Context["component"].(string)
+	if !matchAny(r.Component, component) {
+		return false
+	}
+
+	if len(r.ErrorType) > 0 {
+		var typ string
+		if len(notice.Errors) > 0 {
+			typ = notice.Errors[0].Type
+		}
+		if !matchAny(r.ErrorType, typ) {
+			return false
+		}
+	}
+
+	for k, pattern := range r.Context {
+		v, ok := notice.Context[k]
+		if !ok || !matchAny([]string{pattern}, fmt.Sprint(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyNoticeRules applies the first rule matching the notice. It returns
+// nil if the notice must be dropped.
+func (n *Notifier) applyNoticeRules(notice *Notice) *Notice {
+	for i := range n.opt.NoticeRules {
+		rule := &n.opt.NoticeRules[i]
+		if !rule.match(notice) {
+			continue
+		}
+
+		switch rule.Action {
+		case NoticeActionDrop:
+			return nil
+		case NoticeActionSample:
+			if rand.Float64() >= rule.SampleRate {
+				return nil
+			}
+		case NoticeActionRoute:
+			notice.projectId = rule.ProjectId
+			notice.projectKey = rule.ProjectKey
+		case NoticeActionEscalate:
+			if rule.Escalate != nil {
+				rule.Escalate(notice)
+			}
+		default:
+			n.opt.logger().Printf("unknown notice rule action=%q", rule.Action)
+		}
+		return notice
+	}
+	return notice
+}
@@ -0,0 +1,116 @@
+package gobrake_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NoticeRules", func() {
+	var server *httptest.Server
+	var mu sync.Mutex
+	var sent []string
+
+	BeforeEach(func() {
+		sent = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			sent = append(sent, req.URL.Path+" "+req.Header.Get("Authorization"))
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newNotifier := func(rules ...gobrake.NoticeRule) *gobrake.Notifier {
+		return gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:   1,
+			ProjectKey:  "key",
+			Host:        server.URL,
+			NoticeRules: rules,
+		})
+	}
+
+	send := func(notifier *gobrake.Notifier, severity, component string) {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		if severity != "" {
+			notice.Context["severity"] = severity
+		}
+		notice.Context["component"] = component
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("applies the first matching rule", func() {
+		var escalated []string
+		notifier := newNotifier(
+			gobrake.NoticeRule{
+				Component: []string{"healthcheck/*"},
+				Action:    gobrake.NoticeActionDrop,
+			},
+			gobrake.NoticeRule{
+				Component:  []string{"billing"},
+				Action:     gobrake.NoticeActionRoute,
+				ProjectId:  2,
+				ProjectKey: "billing-key",
+			},
+			gobrake.NoticeRule{
+				Severity: []string{"critical"},
+				Action:   gobrake.NoticeActionEscalate,
+				Escalate: func(notice *gobrake.Notice) {
+					escalated = append(escalated, notice.Context["component"].(string))
+				},
+			},
+			gobrake.NoticeRule{
+				Severity:   []string{"debug"},
+				Action:     gobrake.NoticeActionSample,
+				SampleRate: 0,
+			},
+		)
+		defer notifier.Close()
+
+		send(notifier, "", "healthcheck/db")
+		send(notifier, "critical", "billing")
+		send(notifier, "critical", "api")
+		send(notifier, "debug", "api")
+		send(notifier, "", "api")
+
+		Expect(escalated).To(Equal([]string{"api"}))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(sent).To(Equal([]string{
+			"/api/v3/projects/2/notices Bearer billing-key",
+			"/api/v3/projects/1/notices Bearer key",
+			"/api/v3/projects/1/notices Bearer key",
+		}))
+	})
+
+	It("matches context values", func() {
+		notifier := newNotifier(gobrake.NoticeRule{
+			Context: map[string]string{"tenant": "test-*"},
+			Action:  gobrake.NoticeActionDrop,
+		})
+		defer notifier.Close()
+
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notice.Context["tenant"] = "test-1"
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(sent).To(BeEmpty())
+	})
+})
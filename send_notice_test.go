@@ -0,0 +1,86 @@
+package gobrake_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendNotice", func() {
+	const noticeURL = "https://airbrake.io/locate/123"
+
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+
+	BeforeEach(func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123","url":"` + noticeURL + `"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:          1,
+			ProjectKey:         "key",
+			Host:               server.URL,
+			RecentNoticesLimit: 1,
+		})
+	})
+
+	AfterEach(func() {
+		notifier.Close()
+		server.Close()
+	})
+
+	It("sets notice id and permalink URL", func() {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		id, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("123"))
+		Expect(notice.Id).To(Equal("123"))
+		Expect(notice.URL).To(Equal(noticeURL))
+
+		recent := notifier.RecentNotices()
+		Expect(recent).To(HaveLen(1))
+		Expect(recent[0].URL).To(Equal(noticeURL))
+	})
+
+	It("calls the async callback with id and URL", func() {
+		type result struct {
+			id, url string
+			err     error
+		}
+		ch := make(chan result, 1)
+
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notifier.SendNoticeAsyncCallback(context.Background(), notice, func(id, url string, err error) {
+			ch <- result{id, url, err}
+		})
+
+		var res result
+		Eventually(ch).Should(Receive(&res))
+		Expect(res.err).NotTo(HaveOccurred())
+		Expect(res.id).To(Equal("123"))
+		Expect(res.url).To(Equal(noticeURL))
+	})
+
+	It("calls the async callback when notice is dropped", func() {
+		Expect(notifier.Close()).To(Succeed())
+
+		var called int
+		var cbErr error
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notifier.SendNoticeAsyncCallback(context.Background(), notice, func(id, url string, err error) {
+			called++
+			cbErr = err
+		})
+		Expect(called).To(Equal(1))
+		Expect(cbErr).To(MatchError("gobrake: notifier is closed"))
+	})
+})
@@ -0,0 +1,71 @@
+package gobrake
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// HandleShutdown marks the notifier as shutting down. Errors expected
+// while a server drains, e.g. closed listeners and canceled contexts, are
+// not reported after it is called. http.ErrServerClosed, including errors
+// wrapping it, is never reported. It can be registered with
+// http.Server.RegisterOnShutdown.
+func (n *Notifier) HandleShutdown() {
+	atomic.StoreUint32(&n.shuttingDown, 1)
+}
+
+// ShuttingDown reports whether HandleShutdown was called.
+func (n *Notifier) ShuttingDown() bool {
+	return atomic.LoadUint32(&n.shuttingDown) == 1
+}
+
+func newShutdownFilter(n *Notifier) func(*Notice) *Notice {
+	return func(notice *Notice) *Notice {
+		err, ok := notice.cause.(error)
+		if !ok {
+			return notice
+		}
+		if findError(err, isServerClosed) {
+			// Always expected after Shutdown or Close.
+			return nil
+		}
+		if n.ShuttingDown() && findError(err, isShutdownError) {
+			return nil
+		}
+		return notice
+	}
+}
+
+func isServerClosed(err error) bool {
+	return err == http.ErrServerClosed
+}
+
+func isShutdownError(err error) bool {
+	switch err {
+	case http.ErrServerClosed, context.Canceled, context.DeadlineExceeded:
+		return true
+	}
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// findError reports whether fn returns true for the error or any error
+// it wraps using Unwrap or Cause.
+func findError(err error, fn func(error) bool) bool {
+	for err != nil {
+		if fn(err) {
+			return true
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
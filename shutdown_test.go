@@ -0,0 +1,75 @@
+package gobrake_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type wrappedError struct {
+	err error
+}
+
+func (e wrappedError) Error() string { return "wrapped: " + e.err.Error() }
+func (e wrappedError) Unwrap() error { return e.err }
+
+var _ = Describe("HandleShutdown", func() {
+	var notifier *gobrake.Notifier
+	var sent int32
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&sent, 0)
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&sent, 1)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	send := func(err error) {
+		notifier.Notify(err, nil)
+		notifier.Flush()
+	}
+
+	It("ignores http.ErrServerClosed", func() {
+		send(http.ErrServerClosed)
+		send(wrappedError{http.ErrServerClosed})
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(0)))
+	})
+
+	It("reports canceled context before shutdown", func() {
+		send(context.Canceled)
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(1)))
+	})
+
+	It("ignores shutdown-phase errors after shutdown", func() {
+		notifier.HandleShutdown()
+		Expect(notifier.ShuttingDown()).To(BeTrue())
+
+		send(wrappedError{context.Canceled})
+		send(fmt.Errorf("accept tcp [::]:8080: use of closed network connection"))
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(0)))
+
+		send(errors.New("unexpected"))
+		Expect(atomic.LoadInt32(&sent)).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,73 @@
+package gobrake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	signatureHeader          = "X-Gobrake-Signature"
+	signatureTimestampHeader = "X-Gobrake-Timestamp"
+)
+
+// maxSignatureSkew is how far in the future signature timestamps can be
+// to allow for clock skew between the service and the relay.
+const maxSignatureSkew = time.Minute
+
+// signRequest signs the request with NotifierOptions.SigningKey so a
+// relay can verify that the payload comes from a trusted service. The
+// signature is HMAC-SHA256 of the unix timestamp, the method, the path
+// and the body separated by newlines, so a signed payload can not be
+// replayed to another endpoint.
+func (opt *NotifierOptions) signRequest(req *http.Request, body []byte) {
+	if len(opt.SigningKey) == 0 {
+		return
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(signatureTimestampHeader, ts)
+	req.Header.Set(signatureHeader, "sha256="+signPayload(opt.SigningKey, ts, req, body))
+}
+
+func signPayload(key []byte, ts string, req *http.Request, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(req.Method))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(req.URL.EscapedPath()))
+	mac.Write([]byte{'\n'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the request contains a valid signature
+// of its method, path and the body. It is intended for relays receiving
+// payloads signed using NotifierOptions.SigningKey. Signatures older than
+// maxAge or more than a minute in the future are rejected when maxAge is
+// positive.
+func VerifySignature(key []byte, req *http.Request, body []byte, maxAge time.Duration) bool {
+	ts := req.Header.Get(signatureTimestampHeader)
+	sig := req.Header.Get(signatureHeader)
+	if ts == "" || sig == "" {
+		return false
+	}
+
+	if maxAge > 0 {
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+		age := time.Since(time.Unix(unix, 0))
+		if age > maxAge || age < -maxSignatureSkew {
+			return false
+		}
+	}
+
+	want := "sha256=" + signPayload(key, ts, req, body)
+	return hmac.Equal([]byte(sig), []byte(want))
+}
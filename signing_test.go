@@ -0,0 +1,97 @@
+package gobrake_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("payload signing", func() {
+	var server *httptest.Server
+	var notifier *gobrake.Notifier
+	var mu sync.Mutex
+	var verified map[string]bool
+	var replayed bool
+	key := []byte("secret")
+
+	BeforeEach(func() {
+		verified = make(map[string]bool)
+		replayed = true
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			// The same payload sent to another endpoint.
+			u := *req.URL
+			u.Path = "/api/v5/projects/1/check-ins/nightly"
+			other := req.WithContext(context.Background())
+			other.URL = &u
+
+			mu.Lock()
+			verified[req.URL.Path] = gobrake.VerifySignature(key, req, b, time.Minute)
+			replayed = replayed && gobrake.VerifySignature(key, other, b, time.Minute)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server = httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			Host:                server.URL,
+			DisableRemoteConfig: true,
+			SigningKey:          key,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+		server.Close()
+	})
+
+	It("signs notices", func() {
+		notice := notifier.Notice("hello", nil, 0)
+		_, err := notifier.SendNotice(notice)
+		Expect(err).NotTo(HaveOccurred())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(verified).To(Equal(map[string]bool{"/api/v3/projects/1/notices": true}))
+		Expect(replayed).To(BeFalse())
+	})
+
+	It("signs stats and breakdowns", func() {
+		_, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+		metric.AddGroup("sql", time.Millisecond)
+		metric.End = metric.Start.Add(2 * time.Millisecond)
+		Expect(notifier.NotifyRouteMetric(metric)).To(Succeed())
+		Expect(notifier.FlushContext(context.Background())).To(Succeed())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(verified).To(Equal(map[string]bool{
+			"/api/v5/projects/1/routes-stats":      true,
+			"/api/v5/projects/1/routes-breakdowns": true,
+		}))
+		Expect(replayed).To(BeFalse())
+	})
+
+	It("rejects tampered payloads", func() {
+		req := httptest.NewRequest("PUT", "/api/v5/projects/1/routes-stats", nil)
+		req.Header.Set("X-Gobrake-Timestamp", "1")
+		req.Header.Set("X-Gobrake-Signature", "sha256=00")
+		Expect(gobrake.VerifySignature(key, req, []byte("{}"), 0)).To(BeFalse())
+	})
+})
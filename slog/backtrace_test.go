@@ -0,0 +1,40 @@
+//go:build go1.21
+// +build go1.21
+
+package slog_test
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake/gobraketest"
+	gobrakeslog "github.com/airbrake/gobrake/slog"
+)
+
+func TestHandlerAttributesNoticeToCaller(t *testing.T) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	logger := slog.New(gobrakeslog.NewHandler(notifier, nil, slog.LevelError))
+
+	logger.Error("request failed", "err", errors.New("boom"))
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if got := notice.Context["component"]; got != "github.com/airbrake/gobrake/slog_test" {
+		t.Errorf("got component %v", got)
+	}
+	if got := notice.Context["action"]; got != "TestHandlerAttributesNoticeToCaller" {
+		t.Errorf("got action %v", got)
+	}
+	frame := notice.Errors[0].Backtrace[0]
+	if !strings.HasSuffix(frame.File, "backtrace_test.go") || frame.Func != "TestHandlerAttributesNoticeToCaller" {
+		t.Errorf("got frame %+v", frame)
+	}
+}
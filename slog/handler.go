@@ -0,0 +1,169 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/airbrake/gobrake"
+)
+
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// Handler is a slog.Handler that sends records at or above the minimum
+// level to Airbrake and passes all records through to an inner handler.
+type Handler struct {
+	notifier *gobrake.Notifier
+	inner    slog.Handler
+	level    slog.Leveler
+
+	attrs  []groupedAttr
+	groups []string
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns handler that reports records with level >= level
+// using the notifier. inner may be nil.
+func NewHandler(notifier *gobrake.Notifier, inner slog.Handler, level slog.Leveler) *Handler {
+	if level == nil {
+		level = slog.LevelError
+	}
+	return &Handler{
+		notifier: notifier,
+		inner:    inner,
+		level:    level,
+	}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level >= h.level.Level() {
+		return true
+	}
+	return h.inner != nil && h.inner.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level.Level() {
+		h.notify(ctx, r)
+	}
+
+	if h.inner != nil && h.inner.Enabled(ctx, r.Level) {
+		return h.inner.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *Handler) notify(ctx context.Context, r slog.Record) {
+	// h.attrs can have spare capacity after WithAttrs and is shared by
+	// concurrent calls, so it is clipped before appending.
+	attrs := h.attrs[:len(h.attrs):len(h.attrs)]
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, groupedAttr{groups: h.groups, attr: attr})
+		return true
+	})
+
+	var e interface{} = r.Message
+	for _, a := range attrs {
+		if len(a.groups) > 0 || (a.attr.Key != "err" && a.attr.Key != "error") {
+			continue
+		}
+		if err, ok := a.attr.Value.Resolve().Any().(error); ok {
+			e = err
+		}
+	}
+
+	notice := h.notifier.Notice(e, nil, 0)
+
+	for _, a := range attrs {
+		params := notice.Params
+		for _, g := range a.groups {
+			m, ok := params[g].(map[string]interface{})
+			if !ok {
+				m = make(map[string]interface{})
+				params[g] = m
+			}
+			params = m
+		}
+		setAttr(params, a.attr)
+	}
+	if e != r.Message && r.Message != "" {
+		notice.Params["message"] = r.Message
+	}
+	notice.Context["severity"] = severity(r.Level)
+
+	// The record context is usually canceled soon after logging, e.g. when
+	// the HTTP request is done, so only its values are kept.
+	h.notifier.SendNoticeAsyncContext(gobrake.WithoutCancel(ctx), notice)
+}
+
+func setAttr(params map[string]interface{}, attr slog.Attr) {
+	v := attr.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		if attr.Key != "" {
+			params[attr.Key] = v.Any()
+		}
+		return
+	}
+
+	group := v.Group()
+	if len(group) == 0 {
+		return
+	}
+	if attr.Key != "" {
+		m := make(map[string]interface{}, len(group))
+		params[attr.Key] = m
+		params = m
+	}
+	for _, a := range group {
+		setAttr(params, a)
+	}
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := h.clone()
+	for _, attr := range attrs {
+		h2.attrs = append(h2.attrs, groupedAttr{groups: h.groups, attr: attr})
+	}
+	if h.inner != nil {
+		h2.inner = h.inner.WithAttrs(attrs)
+	}
+	return h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	if h.inner != nil {
+		h2.inner = h.inner.WithGroup(name)
+	}
+	return h2
+}
+
+func (h *Handler) clone() *Handler {
+	h2 := *h
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	h2.groups = h.groups[:len(h.groups):len(h.groups)]
+	return &h2
+}
+
+func severity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "error"
+	case level >= slog.LevelWarn:
+		return "warning"
+	case level >= slog.LevelInfo:
+		return "info"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,119 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/airbrake/gobrake/gobraketest"
+)
+
+func newTestHandler(t *testing.T, inner slog.Handler) (*Handler, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	return NewHandler(notifier, inner, slog.LevelError), server
+}
+
+func TestHandlerReportsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	h, server := newTestHandler(t, slog.NewTextHandler(&buf, nil))
+	logger := slog.New(h)
+
+	logger.Info("started")
+	logger.Error("request failed", "err", errors.New("boom"), "user", 42)
+
+	server.Flush(t, h.notifier)
+	notices := server.Notices()
+	notice := notices[0]
+	if got := notice.Errors[0].Message; got != "boom" {
+		t.Fatalf("got message %q, wanted boom", got)
+	}
+	if got := notice.Params["message"]; got != "request failed" {
+		t.Fatalf("got message param %v", got)
+	}
+	if got := notice.Params["user"]; got != float64(42) {
+		t.Fatalf("got user param %v", got)
+	}
+	if got := notice.Context["severity"]; got != "error" {
+		t.Fatalf("got severity %v", got)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("started")) {
+		t.Fatalf("inner handler did not get the info record: %q", buf.String())
+	}
+}
+
+func TestHandlerGroupsAttrs(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	logger := slog.New(h).With("service", "api").WithGroup("req").With("id", "r1")
+
+	logger.Error("failed", slog.Group("db", slog.String("table", "users")))
+
+	server.Flush(t, h.notifier)
+	notice := server.Notices()[0]
+	if got := notice.Params["service"]; got != "api" {
+		t.Fatalf("got service param %v", got)
+	}
+	req, ok := notice.Params["req"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got req param %#v", notice.Params["req"])
+	}
+	if req["id"] != "r1" {
+		t.Fatalf("got req.id %v", req["id"])
+	}
+	db, ok := req["db"].(map[string]interface{})
+	if !ok || db["table"] != "users" {
+		t.Fatalf("got req.db %#v", req["db"])
+	}
+}
+
+func TestHandlerConcurrentWithAttrs(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	// Three attrs leave spare capacity in the attrs slice.
+	logger := slog.New(h.WithAttrs([]slog.Attr{
+		slog.String("a", "1"),
+		slog.String("b", "2"),
+		slog.String("c", "3"),
+	}))
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logger.Error("failed", "worker", fmt.Sprint(i))
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[interface{}]bool)
+	server.Flush(t, h.notifier)
+	for _, notice := range server.Notices() {
+		if notice.Params["a"] != "1" || notice.Params["b"] != "2" || notice.Params["c"] != "3" {
+			t.Fatalf("got params %v", notice.Params)
+		}
+		seen[notice.Params["worker"]] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("got %d distinct workers, wanted %d", len(seen), n)
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	ctx := context.Background()
+	if h.Enabled(ctx, slog.LevelInfo) {
+		t.Fatal("info is enabled without inner handler")
+	}
+	if !h.Enabled(ctx, slog.LevelError) {
+		t.Fatal("error is not enabled")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/airbrake/gobrake"
+)
+
+type logger struct {
+	l     *slog.Logger
+	level slog.Level
+}
+
+// NewLogger returns gobrake.Logger that writes internal diagnostics to l
+// at the given level.
+func NewLogger(l *slog.Logger, level slog.Level) gobrake.Logger {
+	return &logger{
+		l:     l,
+		level: level,
+	}
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	l.l.Log(context.Background(), l.level, fmt.Sprintf(format, v...),
+		slog.String("component", "gobrake"))
+}
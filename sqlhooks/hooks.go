@@ -0,0 +1,96 @@
+// Package sqlhooks provides hooks for github.com/qustavo/sqlhooks that
+// attribute query time to the "sql" group of the route metric carried by
+// the query context and, when created with a notifier, report queries
+// stats and failed queries.
+//
+//	sql.Register("postgres-gobrake", sqlhooks.Wrap(&pq.Driver{}, gobrakesqlhooks.New(notifier)))
+//	db, err := sql.Open("postgres-gobrake", dsn)
+//	rows, err := db.QueryContext(ctx, query)
+package sqlhooks
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/airbrake/gobrake"
+)
+
+type startKey struct{}
+
+// Hooks implements sqlhooks.Hooks and sqlhooks.OnErrorer.
+type Hooks struct {
+	notifier *gobrake.Notifier
+	group    string
+}
+
+// New returns hooks that add query durations to the "sql" group. Queries
+// stats are reported and failed queries are notified when notifier is
+// not nil.
+func New(notifier *gobrake.Notifier) *Hooks {
+	return &Hooks{
+		notifier: notifier,
+		group:    "sql",
+	}
+}
+
+// Before records the query start time.
+func (h *Hooks) Before(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+// After adds the query duration to the route metric.
+func (h *Hooks) After(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
+	h.finish(ctx, query)
+	return ctx, nil
+}
+
+// OnError adds the duration of the failed query to the route metric and
+// reports the error. driver.ErrSkip is not an error of the query and is
+// ignored. Canceled queries and driver.ErrBadConn, which database/sql
+// retries on another connection, are not reported.
+func (h *Hooks) OnError(ctx context.Context, err error, query string, args ...interface{}) error {
+	if err == driver.ErrSkip {
+		return err
+	}
+
+	h.finish(ctx, query)
+	if h.notifier != nil && reportable(err) {
+		notice := h.notifier.Notice(err, nil, 1)
+		notice.Context["component"] = "sql"
+		notice.Params["query"] = query
+		h.notifier.SendNoticeAsync(notice)
+	}
+	return err
+}
+
+func reportable(err error) bool {
+	switch err {
+	case context.Canceled, context.DeadlineExceeded, driver.ErrBadConn:
+		return false
+	}
+	return true
+}
+
+func (h *Hooks) finish(ctx context.Context, query string) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	end := time.Now()
+	metric := gobrake.ContextRouteMetric(ctx)
+	metric.AddGroup(h.group, end.Sub(start))
+
+	if h.notifier == nil {
+		return
+	}
+	q := &gobrake.QueryInfo{
+		Query: query,
+		Start: start,
+		End:   end,
+	}
+	if metric != nil {
+		q.Method, q.Route = metric.Method, metric.Route
+	}
+	h.notifier.NotifyQuery(q)
+}
@@ -0,0 +1,142 @@
+package sqlhooks
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/airbrake/gobrake"
+	"github.com/airbrake/gobrake/gobraketest"
+)
+
+func newTestHooks(t *testing.T) (*Hooks, *gobrake.Notifier, *gobraketest.Server) {
+	server := gobraketest.NewServer()
+	notifier := server.NewNotifier()
+	t.Cleanup(func() { notifier.Close() })
+	return New(notifier), notifier, server
+}
+
+func TestHooksRecordQueries(t *testing.T) {
+	hooks, notifier, server := newTestHooks(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/users")
+
+	const query = "SELECT * FROM users WHERE id = $1"
+	ctx, err := hooks.Before(ctx, query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := hooks.After(ctx, query, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := metric.Groups()["sql"]; got < 2*time.Millisecond {
+		t.Errorf("got sql group %s, wanted at least 2ms", got)
+	}
+
+	server.Flush(t, notifier)
+	queries := server.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("got %d queries, wanted 1", len(queries))
+	}
+	q := queries[0]
+	if q.Method != "GET" || q.Route != "/users" || q.Query != query || q.Count != 1 {
+		t.Errorf("got query %+v", q)
+	}
+	if q.Sum < 2 {
+		t.Errorf("got sum %f, wanted at least 2ms", q.Sum)
+	}
+}
+
+func TestHooksWithoutStartTime(t *testing.T) {
+	hooks, notifier, server := newTestHooks(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/users")
+
+	// After is called with the context returned by Before, so a query
+	// without it is not timed.
+	if _, err := hooks.After(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metric.Groups()["sql"]; ok {
+		t.Error("sql group is recorded")
+	}
+	server.Flush(t, notifier)
+	if queries := server.Queries(); len(queries) != 0 {
+		t.Errorf("got queries %+v", queries)
+	}
+}
+
+func TestHooksReportErrors(t *testing.T) {
+	hooks, notifier, server := newTestHooks(t)
+	ctx, metric := gobrake.NewRouteMetric(context.Background(), "POST", "/orders")
+
+	const query = "INSERT INTO orders VALUES ($1)"
+	ctx, err := hooks.Before(ctx, query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := errors.New("duplicate key")
+	if err := hooks.OnError(ctx, wanted, query, 1); err != wanted {
+		t.Fatalf("got %v, wanted %v", err, wanted)
+	}
+
+	notices, err := server.WaitForNotices(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := notices[0]
+	if notice.Errors[0].Message != "duplicate key" || notice.Params["query"] != query {
+		t.Errorf("got notice %+v", notice)
+	}
+	if _, ok := metric.Groups()["sql"]; !ok {
+		t.Error("sql group is not recorded")
+	}
+	server.Flush(t, notifier)
+	if queries := server.Queries(); len(queries) != 1 {
+		t.Errorf("got queries %+v", queries)
+	}
+}
+
+func TestHooksIgnoreErrSkip(t *testing.T) {
+	hooks, notifier, server := newTestHooks(t)
+
+	ctx, err := hooks.Before(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hooks.OnError(ctx, driver.ErrSkip, "SELECT 1"); err != driver.ErrSkip {
+		t.Fatalf("got %v, wanted driver.ErrSkip", err)
+	}
+
+	server.Flush(t, notifier)
+	if queries := server.Queries(); len(queries) != 0 {
+		t.Errorf("got queries %+v", queries)
+	}
+	if len(server.Notices()) != 0 {
+		t.Error("driver.ErrSkip is reported")
+	}
+}
+
+func TestHooksIgnoreCanceledQueries(t *testing.T) {
+	hooks, notifier, server := newTestHooks(t)
+
+	for _, wanted := range []error{context.Canceled, context.DeadlineExceeded, driver.ErrBadConn} {
+		ctx, err := hooks.Before(context.Background(), "SELECT 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := hooks.OnError(ctx, wanted, "SELECT 1"); err != wanted {
+			t.Fatalf("got %v, wanted %v", err, wanted)
+		}
+	}
+
+	server.Flush(t, notifier)
+	if queries := server.Queries(); len(queries) != 1 || queries[0].Count != 3 {
+		t.Errorf("got queries %+v", queries)
+	}
+	if len(server.Notices()) != 0 {
+		t.Errorf("got %d notices, wanted 0", len(server.Notices()))
+	}
+}
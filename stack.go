@@ -9,8 +9,11 @@ import (
 
 // getBacktrace returns the stacktrace associated with e. If e is an
 // error from the errors package its stacktrace is extracted, otherwise
-// the current stacktrace is collected end returned.
-func getBacktrace(e interface{}, skip int) (string, []StackFrame) {
+// the current stacktrace is collected end returned starting from the
+// top in-app frame, so notifier and runtime frames such as gopanic are
+// skipped regardless of skip. The package and function of the top in-app
+// frame are returned too.
+func getBacktrace(e interface{}, skip int) (string, string, []StackFrame) {
 	if err, ok := e.(stackTracer); ok {
 		return backtraceFromErrorWithStackTrace(err)
 	}
@@ -20,7 +23,8 @@ func getBacktrace(e interface{}, skip int) (string, []StackFrame) {
 	n := runtime.Callers(skip+1, pcs[:])
 	ff := runtime.CallersFrames(pcs[:n])
 
-	var firstPkg string
+	var firstPkg, firstFn string
+	first := -1
 	frames := make([]StackFrame, 0)
 	for {
 		f, ok := ff.Next()
@@ -29,15 +33,18 @@ func getBacktrace(e interface{}, skip int) (string, []StackFrame) {
 		}
 
 		pkg, fn := splitPackageFuncName(f.Function)
-		if firstPkg == "" && pkg != "runtime" {
-			firstPkg = pkg
-		}
-
 		if stackFilter(pkg, fn, f.File, f.Line) {
 			frames = frames[:0]
+			firstPkg, firstFn = "", ""
+			first = -1
 			continue
 		}
 
+		if first == -1 && isInAppPackage(pkg) {
+			firstPkg, firstFn = pkg, fn
+			first = len(frames)
+		}
+
 		frames = append(frames, StackFrame{
 			File: f.File,
 			Line: f.Line,
@@ -45,7 +52,29 @@ func getBacktrace(e interface{}, skip int) (string, []StackFrame) {
 		})
 	}
 
-	return firstPkg, frames
+	if first > 0 {
+		frames = frames[first:]
+	}
+	return firstPkg, firstFn, frames
+}
+
+const gobrakePackage = "github.com/airbrake/gobrake"
+
+// isInAppPackage reports whether frames of the package belong to the
+// application rather than to the runtime, the notifier and its
+// subpackages, or the logging libraries the subpackages hook into.
+func isInAppPackage(pkg string) bool {
+	switch pkg {
+	case "runtime", "log/slog", "github.com/sirupsen/logrus":
+		return false
+	}
+	if pkg == gobrakePackage || strings.HasPrefix(pkg, gobrakePackage+"/") {
+		// External test packages and test fixtures stand in for the
+		// application.
+		return strings.HasSuffix(pkg, "_test") ||
+			strings.HasPrefix(pkg, gobrakePackage+"/internal/testpkg")
+	}
+	return true
 }
 
 func splitPackageFuncName(funcName string) (string, string) {
@@ -72,15 +101,15 @@ type stackTracer interface {
 }
 
 // backtraceFromErrorWithStackTrace extracts the stacktrace from e.
-func backtraceFromErrorWithStackTrace(e stackTracer) (string, []StackFrame) {
+func backtraceFromErrorWithStackTrace(e stackTracer) (string, string, []StackFrame) {
 	stackTrace := e.StackTrace()
 
-	var firstPkg string
+	var firstPkg, firstFn string
 	frames := make([]StackFrame, 0)
 	for _, f := range stackTrace {
 		pkg, fn := splitPackageFuncName(f.Function)
-		if firstPkg == "" {
-			firstPkg = pkg
+		if firstPkg == "" && isInAppPackage(pkg) {
+			firstPkg, firstFn = pkg, fn
 		}
 
 		frames = append(frames, StackFrame{
@@ -90,5 +119,5 @@ func backtraceFromErrorWithStackTrace(e stackTracer) (string, []StackFrame) {
 		})
 	}
 
-	return firstPkg, frames
+	return firstPkg, firstFn, frames
 }
@@ -0,0 +1,190 @@
+package gobrake
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NotifierStats contains notifier self-metrics which can be used to alert
+// when error reporting itself is failing.
+type NotifierStats struct {
+	// Notices accepted by Airbrake.
+	NoticesSent uint32 `json:"notices_sent"`
+	// Notices dropped without sending because of rate limits, full queue,
+	// open circuit breaker, remote config or size limit.
+	NoticesDropped uint32 `json:"notices_dropped"`
+	// Notices that failed to be sent.
+	NoticesFailed uint32 `json:"notices_failed"`
+	// Notices abandoned by CloseContext or FlushContext.
+	NoticesAbandoned uint32 `json:"notices_abandoned"`
+
+	// Successful and failed flushes of requests stats and breakdowns.
+	StatsFlushed uint32 `json:"stats_flushed"`
+	StatsFailed  uint32 `json:"stats_failed"`
+	// Requests stats and breakdowns routes abandoned by CloseContext or
+	// FlushContext.
+	RoutesAbandoned uint32 `json:"routes_abandoned"`
+	// Samples of route breakdowns and queries stats dropped because they
+	// were older than StatsMaxAge. Expired requests stats are reported to
+	// Airbrake with the stats instead.
+	StatsExpired uint32 `json:"stats_expired"`
+
+	// Number of notices waiting to be sent.
+	QueueDepth int `json:"queue_depth"`
+	// Duration of the last send to Airbrake.
+	LastSendLatency time.Duration `json:"last_send_latency"`
+	// Whether the circuit breaker is open.
+	BreakerOpen bool `json:"breaker_open"`
+
+	// T-digests sent with the last flush of requests stats and route
+	// breakdowns.
+	RoutesTDigest     TDigestStats `json:"routes_tdigest"`
+	BreakdownsTDigest TDigestStats `json:"breakdowns_tdigest"`
+
+	// Latencies of outbound requests by host reported by
+	// RoundTripper.WithHostStats.
+	OutboundHosts map[string]HostStats `json:"outbound_hosts,omitempty"`
+}
+
+// notifierMetrics counts notifier self-metrics. Methods are safe to call
+// on nil metrics.
+type notifierMetrics struct {
+	noticesSent    uint32 // atomic
+	noticesDropped uint32 // atomic
+	noticesFailed  uint32 // atomic
+	statsFlushed   uint32 // atomic
+	statsFailed    uint32 // atomic
+	statsExpired   uint32 // atomic
+
+	lastSendLatency uint32 // atomic, microseconds
+
+	mu         sync.Mutex
+	routes     TDigestStats
+	breakdowns TDigestStats
+}
+
+func isNoticeDropped(err error) bool {
+	switch err {
+	case errQueueFull, errClosed, errIPRateLimited, errAccountRateLimited,
+		errBreakerOpen, errErrorsDisabledRemotely:
+		return true
+	}
+	_, ok := err.(*noticeTooBigError)
+	return ok
+}
+
+func (m *notifierMetrics) notice(id string, err error, took time.Duration) {
+	if m == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		if id == "" {
+			// Notice is ignored.
+			return
+		}
+		atomic.AddUint32(&m.noticesSent, 1)
+	case isNoticeDropped(err):
+		atomic.AddUint32(&m.noticesDropped, 1)
+		return
+	default:
+		atomic.AddUint32(&m.noticesFailed, 1)
+	}
+	m.latency(took)
+}
+
+func (m *notifierMetrics) noticeDropped() {
+	if m == nil {
+		return
+	}
+	atomic.AddUint32(&m.noticesDropped, 1)
+}
+
+func (m *notifierMetrics) flush(err error, took time.Duration) {
+	if m == nil {
+		return
+	}
+
+	if err == nil {
+		atomic.AddUint32(&m.statsFlushed, 1)
+	} else {
+		atomic.AddUint32(&m.statsFailed, 1)
+	}
+	if err != errBreakerOpen {
+		m.latency(took)
+	}
+}
+
+func (m *notifierMetrics) expired(count int) {
+	if m == nil {
+		return
+	}
+	atomic.AddUint32(&m.statsExpired, uint32(count))
+}
+
+func (m *notifierMetrics) latency(took time.Duration) {
+	us := took / time.Microsecond
+	if us > 1<<32-1 {
+		us = 1<<32 - 1
+	}
+	atomic.StoreUint32(&m.lastSendLatency, uint32(us))
+}
+
+func (m *notifierMetrics) routesTDigest(d TDigestStats) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.routes = d
+	m.mu.Unlock()
+}
+
+func (m *notifierMetrics) breakdownsTDigest(d TDigestStats) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.breakdowns = d
+	m.mu.Unlock()
+}
+
+// Stats returns notifier self-metrics.
+func (n *Notifier) Stats() NotifierStats {
+	m := n.metrics
+
+	m.mu.Lock()
+	routes, breakdowns := m.routes, m.breakdowns
+	m.mu.Unlock()
+
+	return NotifierStats{
+		NoticesSent:      atomic.LoadUint32(&m.noticesSent),
+		NoticesDropped:   atomic.LoadUint32(&m.noticesDropped),
+		NoticesFailed:    atomic.LoadUint32(&m.noticesFailed),
+		NoticesAbandoned: atomic.LoadUint32(&n.abandonedNotices),
+
+		StatsFlushed:    atomic.LoadUint32(&m.statsFlushed),
+		StatsFailed:     atomic.LoadUint32(&m.statsFailed),
+		RoutesAbandoned: atomic.LoadUint32(&n.abandonedRoutes),
+		StatsExpired:    atomic.LoadUint32(&m.statsExpired),
+
+		QueueDepth:      int(atomic.LoadInt32(&n.inFlight)),
+		LastSendLatency: time.Duration(atomic.LoadUint32(&m.lastSendLatency)) * time.Microsecond,
+		BreakerOpen:     n.breaker.Open(),
+
+		RoutesTDigest:     routes,
+		BreakdownsTDigest: breakdowns,
+
+		OutboundHosts: n.hosts.Snapshot(),
+	}
+}
+
+// PublishExpvar publishes notifier stats as the expvar variable with the
+// given name. Like expvar.Publish, it panics if the name is already used.
+func (n *Notifier) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return n.Stats()
+	}))
+}
@@ -0,0 +1,130 @@
+package gobrake_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notifier.Stats", func() {
+	var notifier *gobrake.Notifier
+	var status int32
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&status, http.StatusCreated)
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	send := func() {
+		notice := notifier.Notice(errors.New("hello"), nil, 0)
+		notifier.SendNotice(notice)
+	}
+
+	It("counts sent, failed and dropped notices", func() {
+		send()
+		atomic.StoreInt32(&status, http.StatusInternalServerError)
+		send()
+		atomic.StoreInt32(&status, 420)
+		send()
+
+		stats := notifier.Stats()
+		Expect(stats.NoticesSent).To(Equal(uint32(1)))
+		Expect(stats.NoticesFailed).To(Equal(uint32(1)))
+		Expect(stats.NoticesDropped).To(Equal(uint32(1)))
+		Expect(stats.LastSendLatency).To(BeNumerically(">", 0))
+		Expect(stats.QueueDepth).To(Equal(0))
+		Expect(stats.BreakerOpen).To(BeFalse())
+	})
+
+	It("reports t-digest diagnostics of the last flush", func() {
+		// Requests are bucketed by end time, so it is shared to keep them
+		// in a single minute.
+		end := time.Now()
+		for i := 0; i < 1000; i++ {
+			err := notifier.NotifyRequest(&gobrake.RequestInfo{
+				Method:     "GET",
+				Route:      "/hello",
+				StatusCode: 200,
+				Start:      end.Add(-time.Duration(i) * time.Millisecond),
+				End:        end,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(notifier.FlushContext(context.Background())).NotTo(HaveOccurred())
+
+		td := notifier.Stats().RoutesTDigest
+		Expect(td.Digests).To(Equal(1))
+		Expect(td.Centroids).To(Equal(td.MaxCentroids))
+		Expect(td.Centroids).To(BeNumerically(">", 0))
+		Expect(td.Centroids).To(BeNumerically("<", 1000))
+		Expect(td.MemoryBytes).To(Equal(td.Centroids * 12))
+		Expect(td.EncodedBytes).To(BeNumerically(">", 0))
+		Expect(td.MaxRankError).To(BeNumerically(">", 0))
+		Expect(td.MaxRankError).To(BeNumerically("<", 0.1))
+	})
+
+	It("counts expired breakdowns and queries", func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:   1,
+			ProjectKey:  "key",
+			Host:        server.URL,
+			StatsMaxAge: time.Minute,
+		})
+		defer notifier.Close()
+
+		start := time.Now().Add(-time.Hour)
+		_, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+		metric.StatusCode = 200
+		metric.Start, metric.End = start, start.Add(time.Millisecond)
+		metric.AddGroup("sql", time.Millisecond)
+		Expect(notifier.NotifyRouteMetric(metric)).To(Succeed())
+		Expect(notifier.NotifyQuery(&gobrake.QueryInfo{
+			Query: "SELECT 1",
+			Start: start,
+			End:   start.Add(time.Millisecond),
+		})).To(Succeed())
+
+		Expect(notifier.FlushContext(context.Background())).NotTo(HaveOccurred())
+		Expect(notifier.Stats().StatsExpired).To(Equal(uint32(2)))
+	})
+
+	It("publishes stats with expvar", func() {
+		send()
+		notifier.PublishExpvar("gobrake_stats_test")
+
+		var stats gobrake.NotifierStats
+		err := json.Unmarshal([]byte(expvar.Get("gobrake_stats_test").String()), &stats)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.NoticesSent).To(Equal(uint32(1)))
+	})
+})
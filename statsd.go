@@ -0,0 +1,84 @@
+package gobrake
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// statsdMirror emits route timings to a StatsD or DogStatsD daemon.
+type statsdMirror struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+func newStatsdMirror(opt *NotifierOptions) (*statsdMirror, error) {
+	conn, err := net.Dial("udp", opt.StatsdAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdMirror{
+		conn:   conn,
+		prefix: opt.StatsdPrefix,
+		tags:   opt.StatsdDogTags,
+	}, nil
+}
+
+// Timing sends a timing metric for the route. Errors are ignored because
+// StatsD delivery is best-effort and must not slow down requests.
+func (m *statsdMirror) Timing(key *routeKey, ms float64) {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+	buf.Reset()
+
+	buf.WriteString(m.prefix)
+	if m.tags {
+		buf.WriteString("route.timing:")
+		buf.WriteString(strconv.FormatFloat(ms, 'f', -1, 64))
+		buf.WriteString("|ms|#method:")
+		buf.WriteString(statsdTag(key.Method))
+		buf.WriteString(",route:")
+		buf.WriteString(statsdTag(key.Route))
+		buf.WriteString(",status:")
+		buf.WriteString(strconv.Itoa(key.StatusCode))
+	} else {
+		buf.WriteString("route.")
+		buf.WriteString(statsdName(key.Method))
+		buf.WriteByte('.')
+		buf.WriteString(statsdName(key.Route))
+		buf.WriteByte('.')
+		buf.WriteString(strconv.Itoa(key.StatusCode))
+		buf.WriteByte(':')
+		buf.WriteString(strconv.FormatFloat(ms, 'f', -1, 64))
+		buf.WriteString("|ms")
+	}
+
+	_, _ = m.conn.Write(buf.Bytes())
+}
+
+// Close closes the UDP connection.
+func (m *statsdMirror) Close() error {
+	return m.conn.Close()
+}
+
+var statsdNameReplacer = strings.NewReplacer(
+	"/", "_", ".", "_", ":", "_", "|", "_", "@", "_", "#", "_", " ", "_",
+)
+
+func statsdName(s string) string {
+	s = statsdNameReplacer.Replace(strings.Trim(s, "/"))
+	if s == "" {
+		return "root"
+	}
+	return s
+}
+
+var statsdTagReplacer = strings.NewReplacer(
+	",", "_", "|", "_", "#", "_", " ", "_",
+)
+
+func statsdTag(s string) string {
+	return statsdTagReplacer.Replace(s)
+}
@@ -0,0 +1,72 @@
+package gobrake
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("statsdMirror", func() {
+	var conn net.PacketConn
+
+	BeforeEach(func() {
+		var err error
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		conn.Close()
+	})
+
+	read := func() string {
+		b := make([]byte, 512)
+		n, _, err := conn.ReadFrom(b)
+		Expect(err).NotTo(HaveOccurred())
+		return string(b[:n])
+	}
+
+	key := &routeKey{
+		Method:     "GET",
+		Route:      "/hello/:name",
+		StatusCode: 200,
+	}
+
+	It("encodes route in metric name", func() {
+		m, err := newStatsdMirror(&NotifierOptions{
+			StatsdAddr:   conn.LocalAddr().String(),
+			StatsdPrefix: "myapp.",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer m.Close()
+
+		m.Timing(key, 12.5)
+		Expect(read()).To(Equal("myapp.route.GET.hello__name.200:12.5|ms"))
+	})
+
+	It("sends DogStatsD tags", func() {
+		m, err := newStatsdMirror(&NotifierOptions{
+			StatsdAddr:    conn.LocalAddr().String(),
+			StatsdDogTags: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer m.Close()
+
+		m.Timing(key, 12.5)
+		Expect(read()).To(Equal("route.timing:12.5|ms|#method:GET,route:/hello/:name,status:200"))
+	})
+
+	It("is closed with the notifier", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			DisableRemoteConfig: true,
+			StatsdAddr:          conn.LocalAddr().String(),
+		})
+		Expect(notifier.Close()).To(Succeed())
+
+		_, err := notifier.routes.statsd.conn.Write([]byte("hello"))
+		Expect(err).To(HaveOccurred())
+	})
+})
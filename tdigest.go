@@ -0,0 +1,110 @@
+package gobrake
+
+import (
+	"bytes"
+	"fmt"
+
+	tdigest "github.com/caio/go-tdigest"
+)
+
+// TDigestVersion is the version of the t-digest encoding sent in requests
+// stats and breakdowns payloads as tdigestVersion next to tdigest.
+// Payloads without version were sent by older gobrake versions and use
+// version 1 encoding.
+const TDigestVersion = 1
+
+// TDigestStats describes t-digests sent with the last flush of requests
+// stats or route breakdowns and helps to tune TDigestCompression.
+type TDigestStats struct {
+	// Number of t-digests, one per route and group.
+	Digests int `json:"digests"`
+	// Total and maximum per t-digest number of centroids.
+	Centroids    int `json:"centroids"`
+	MaxCentroids int `json:"max_centroids"`
+	// Approximate memory used by centroids in bytes.
+	MemoryBytes int `json:"memory_bytes"`
+	// Size of encoded t-digests in bytes.
+	EncodedBytes int `json:"encoded_bytes"`
+	// Maximum error bound of quantile ranks observed across t-digests,
+	// e.g. 0.005 means that reported p99 lies between p98.5 and p99.5.
+	MaxRankError float64 `json:"max_rank_error"`
+}
+
+// centroidSize is the memory used by a centroid: float64 mean and uint32
+// count.
+const centroidSize = 12
+
+func (d *TDigestStats) add(td *tdigest.TDigest, encoded []byte) {
+	var centroids int
+	var maxCount uint32
+	td.ForEachCentroid(func(mean float64, count uint32) bool {
+		centroids++
+		if count > maxCount {
+			maxCount = count
+		}
+		return true
+	})
+
+	d.Digests++
+	d.Centroids += centroids
+	if centroids > d.MaxCentroids {
+		d.MaxCentroids = centroids
+	}
+	d.MemoryBytes += centroids * centroidSize
+	d.EncodedBytes += len(encoded)
+
+	// Centroids of a single sample are exact, otherwise a quantile can
+	// be off by up to half of the centroid weight.
+	if n := td.Count(); n > 0 && maxCount > 1 {
+		rankErr := float64(maxCount-1) / float64(n) / 2
+		if rankErr > d.MaxRankError {
+			d.MaxRankError = rankErr
+		}
+	}
+}
+
+// EncodedTDigest is a t-digest encoded with the given version.
+type EncodedTDigest struct {
+	Version int
+	Bytes   []byte
+}
+
+func decodeTDigest(d EncodedTDigest, compression uint32) (*tdigest.TDigest, error) {
+	switch d.Version {
+	case 0, 1:
+		return tdigest.FromBytes(bytes.NewReader(d.Bytes), tdigest.Compression(compression))
+	default:
+		return nil, fmt.Errorf("gobrake: unsupported tdigest version=%d", d.Version)
+	}
+}
+
+// MergeTDigests merges t-digests of any supported version, e.g. received
+// by a relay from a mixed-version fleet, and returns the result encoded
+// with TDigestVersion.
+func MergeTDigests(compression uint32, digests ...EncodedTDigest) ([]byte, error) {
+	if compression == 0 {
+		compression = defaultTDigestCompression
+	}
+
+	merged, err := tdigest.New(tdigest.Compression(compression))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range digests {
+		td, err := decodeTDigest(d, compression)
+		if err != nil {
+			return nil, err
+		}
+		err = merged.Merge(td)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = merged.Compress()
+	if err != nil {
+		return nil, err
+	}
+	return merged.AsBytes()
+}
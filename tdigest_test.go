@@ -0,0 +1,39 @@
+package gobrake
+
+import (
+	"bytes"
+
+	tdigest "github.com/caio/go-tdigest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeTDigests", func() {
+	encode := func(values ...float64) []byte {
+		stat := &routeStat{}
+		for _, v := range values {
+			Expect(stat.Add(v)).NotTo(HaveOccurred())
+		}
+		Expect(stat.compress()).NotTo(HaveOccurred())
+		Expect(stat.TDigestVersion).To(Equal(TDigestVersion))
+		return stat.TDigest
+	}
+
+	It("merges current and unversioned t-digests", func() {
+		b, err := MergeTDigests(0,
+			EncodedTDigest{Version: TDigestVersion, Bytes: encode(1, 2)},
+			EncodedTDigest{Bytes: encode(3)},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		td, err := tdigest.FromBytes(bytes.NewReader(b))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(td.Count()).To(Equal(uint64(3)))
+	})
+
+	It("rejects unknown versions", func() {
+		_, err := MergeTDigests(0, EncodedTDigest{Version: 99, Bytes: encode(1)})
+		Expect(err).To(MatchError("gobrake: unsupported tdigest version=99"))
+	})
+})
@@ -0,0 +1,65 @@
+package gobrake
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// ClientCertIdentity returns the identity of the verified TLS client
+// certificate of the request or an empty string. The first URI SAN, e.g.
+// a SPIFFE ID, is preferred over DNS and email SANs and the common name.
+func ClientCertIdentity(req *http.Request) string {
+	cert := verifiedClientCert(req)
+	if cert == nil {
+		return ""
+	}
+
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// ReportsClientIdentity reports whether the notifier is configured with
+// ReportClientCertIdentity. Middlewares use it to skip computing
+// RequestInfo.ClientIdentity, which is discarded otherwise.
+func (n *Notifier) ReportsClientIdentity() bool {
+	return n.opt.ReportClientCertIdentity
+}
+
+// verifiedClientCert returns the leaf of the first verified chain.
+// Unverified peer certificates are ignored, because they can claim any
+// identity.
+func verifiedClientCert(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	chain := req.TLS.VerifiedChains[0]
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain[0]
+}
+
+// setClientCertUser sets the notice user to the identity of the verified
+// TLS client certificate. The certificate itself is not sent.
+func (n *Notice) setClientCertUser(req *http.Request) {
+	cert := verifiedClientCert(req)
+	if cert == nil {
+		return
+	}
+
+	user := map[string]interface{}{
+		"id": ClientCertIdentity(req),
+	}
+	if cert.Subject.CommonName != "" {
+		user["name"] = cert.Subject.CommonName
+	}
+	n.Context["user"] = user
+}
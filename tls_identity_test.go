@@ -0,0 +1,76 @@
+package gobrake_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientCertIdentity", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		spiffe, err := url.Parse("spiffe://cluster.local/ns/default/sa/api")
+		Expect(err).To(BeNil())
+
+		cert := &x509.Certificate{
+			Subject:  pkix.Name{CommonName: "api"},
+			URIs:     []*url.URL{spiffe},
+			DNSNames: []string{"api.default.svc"},
+		}
+
+		req, err = http.NewRequest("GET", "https://example.com/hello", nil)
+		Expect(err).To(BeNil())
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+	})
+
+	It("prefers URI SAN", func() {
+		Expect(gobrake.ClientCertIdentity(req)).To(Equal("spiffe://cluster.local/ns/default/sa/api"))
+	})
+
+	It("ignores unverified certificates", func() {
+		req.TLS.VerifiedChains = nil
+		Expect(gobrake.ClientCertIdentity(req)).To(Equal(""))
+	})
+
+	It("reports identity as notice user when enabled", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:                1,
+			ProjectKey:               "key",
+			DisableRemoteConfig:      true,
+			ReportClientCertIdentity: true,
+		})
+		defer notifier.Close()
+		Expect(notifier.ReportsClientIdentity()).To(BeTrue())
+
+		notice := notifier.Notice(errors.New("hello"), req, 0)
+		Expect(notice.Context["user"]).To(Equal(map[string]interface{}{
+			"id":   "spiffe://cluster.local/ns/default/sa/api",
+			"name": "api",
+		}))
+	})
+
+	It("does not report identity by default", func() {
+		notifier := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:           1,
+			ProjectKey:          "key",
+			DisableRemoteConfig: true,
+		})
+		defer notifier.Close()
+		Expect(notifier.ReportsClientIdentity()).To(BeFalse())
+
+		notice := notifier.Notice(errors.New("hello"), req, 0)
+		Expect(notice.Context).NotTo(HaveKey("user"))
+	})
+})
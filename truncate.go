@@ -0,0 +1,280 @@
+package gobrake
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+const defaultNoticeMaxBacktrace = 100
+
+// Limits of values of notices that exceed NoticeMaxSize. They are applied
+// before values are dropped.
+const (
+	shrinkMaxDepth     = 5
+	shrinkMaxStringLen = 1024
+	shrinkMaxItems     = 128
+)
+
+const truncatedValue = "[Truncated]"
+
+type noticeTooBigError struct {
+	limit int
+}
+
+func (e *noticeTooBigError) Error() string {
+	if e.limit == maxNoticeLen {
+		return "gobrake: notice exceeds 64KB max size limit"
+	}
+	return fmt.Sprintf("gobrake: notice exceeds %d bytes max size limit", e.limit)
+}
+
+// truncator limits params, session and environment values and counts
+// what was truncated. Zero limits are not applied.
+type truncator struct {
+	maxDepth     int
+	maxStringLen int
+	maxItems     int
+
+	truncated int
+}
+
+func (t *truncator) truncate(v interface{}, depth int) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16,
+		uint32, uint64, float32, float64, json.Number:
+		return v
+	case string:
+		return t.truncateString(v)
+	case map[string]interface{}:
+		if t.maxDepth > 0 && depth >= t.maxDepth {
+			t.truncated++
+			return truncatedValue
+		}
+		return t.truncateMap(v, depth)
+	case []interface{}:
+		if t.maxDepth > 0 && depth >= t.maxDepth {
+			t.truncated++
+			return truncatedValue
+		}
+		return t.truncateSlice(v, depth)
+	}
+
+	// Other values are normalized to JSON types, so large structs, maps
+	// and slices are limited too.
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Ptr:
+	default:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return t.truncateString(fmt.Sprint(v))
+	}
+	if (t.maxStringLen <= 0 || len(b) <= t.maxStringLen) &&
+		(t.maxDepth <= 0 || depth+jsonDepth(b) <= t.maxDepth) {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return t.truncateString(string(b))
+	}
+	return t.truncate(normalized, depth)
+}
+
+func (t *truncator) truncateString(s string) string {
+	if t.maxStringLen <= 0 || len(s) <= t.maxStringLen {
+		return s
+	}
+	t.truncated++
+
+	n := t.maxStringLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+func (t *truncator) truncateMap(m map[string]interface{}, depth int) map[string]interface{} {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if t.maxItems > 0 && len(keys) > t.maxItems {
+		t.truncated += len(keys) - t.maxItems
+		keys = keys[:t.maxItems]
+	}
+
+	res := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		res[k] = t.truncate(m[k], depth+1)
+	}
+	return res
+}
+
+func (t *truncator) truncateSlice(s []interface{}, depth int) []interface{} {
+	if t.maxItems > 0 && len(s) > t.maxItems {
+		t.truncated += len(s) - t.maxItems
+		s = s[:t.maxItems]
+	}
+
+	res := make([]interface{}, len(s))
+	for i, v := range s {
+		res[i] = t.truncate(v, depth+1)
+	}
+	return res
+}
+
+// jsonDepth returns nesting depth of JSON encoded objects and arrays.
+func jsonDepth(b []byte) int {
+	var depth, max int
+	var inString, escaped bool
+	for _, c := range b {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
+
+// noticeValues returns params, session and environment of the notice in
+// the order they are shrunk.
+func noticeValues(notice *Notice) []noticeField {
+	return []noticeField{
+		{"params", notice.Params},
+		{"session", notice.Session},
+		{"environment", notice.Env},
+	}
+}
+
+type noticeField struct {
+	name   string
+	values map[string]interface{}
+}
+
+// truncateValues limits notice values and adds number of truncated values
+// per field to the report. It reports whether anything was truncated.
+func (t *truncator) truncateValues(notice *Notice, report map[string]interface{}) bool {
+	var truncated bool
+	for _, f := range noticeValues(notice) {
+		t.truncated = 0
+		for k, v := range f.values {
+			f.values[k] = t.truncate(v, 0)
+		}
+		if t.truncated > 0 {
+			prev, _ := report[f.name].(int)
+			report[f.name] = prev + t.truncated
+			truncated = true
+		}
+	}
+	return truncated
+}
+
+// truncateNotice applies NoticeMaxDepth, NoticeMaxStringLen, NoticeMaxItems
+// and NoticeMaxBacktrace to the notice and records number of truncated
+// values per field in context/truncated.
+func (opt *NotifierOptions) truncateNotice(notice *Notice) {
+	report := make(map[string]interface{})
+
+	if opt.NoticeMaxDepth > 0 || opt.NoticeMaxStringLen > 0 || opt.NoticeMaxItems > 0 {
+		t := &truncator{
+			maxDepth:     opt.NoticeMaxDepth,
+			maxStringLen: opt.NoticeMaxStringLen,
+			maxItems:     opt.NoticeMaxItems,
+		}
+		t.truncateValues(notice, report)
+	}
+
+	var frames int
+	for i := range notice.Errors {
+		e := &notice.Errors[i]
+		if len(e.Backtrace) > opt.NoticeMaxBacktrace {
+			frames += len(e.Backtrace) - opt.NoticeMaxBacktrace
+			e.Backtrace = e.Backtrace[:opt.NoticeMaxBacktrace]
+		}
+	}
+	if frames > 0 {
+		report["backtrace"] = frames
+	}
+
+	if len(report) > 0 {
+		notice.Context["truncated"] = report
+	}
+}
+
+// shrinkNotice makes the notice smaller for the next encoding attempt
+// after it exceeded NoticeMaxSize and reports whether there is anything
+// left to shrink. First code hunks are dropped, then params, session and
+// environment values are limited to shrinkMaxDepth, shrinkMaxStringLen
+// and shrinkMaxItems and finally dropped.
+func shrinkNotice(notice *Notice, size int) bool {
+	report, _ := notice.Context["truncated"].(map[string]interface{})
+	if report == nil {
+		report = make(map[string]interface{})
+		notice.Context["truncated"] = report
+	}
+	if _, ok := report["size"]; !ok {
+		report["size"] = size
+	}
+
+	var hasCode bool
+	for i := range notice.Errors {
+		for j := range notice.Errors[i].Backtrace {
+			frame := &notice.Errors[i].Backtrace[j]
+			if frame.Code != nil {
+				frame.Code = nil
+				hasCode = true
+			}
+		}
+	}
+	if hasCode {
+		report["code"] = true
+		return true
+	}
+
+	t := &truncator{
+		maxDepth:     shrinkMaxDepth,
+		maxStringLen: shrinkMaxStringLen,
+		maxItems:     shrinkMaxItems,
+	}
+	if t.truncateValues(notice, report) {
+		return true
+	}
+
+	// One field at a time, so params are dropped before the rest.
+	for _, f := range noticeValues(notice) {
+		var n int
+		for k, v := range f.values {
+			if v != truncatedValue {
+				f.values[k] = truncatedValue
+				n++
+			}
+		}
+		if n > 0 {
+			prev, _ := report[f.name].(int)
+			report[f.name] = prev + n
+			return true
+		}
+	}
+	return false
+}
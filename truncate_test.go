@@ -0,0 +1,158 @@
+package gobrake
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("truncateNotice", func() {
+	var opt *NotifierOptions
+
+	BeforeEach(func() {
+		opt = &NotifierOptions{
+			NoticeMaxDepth:     2,
+			NoticeMaxStringLen: 8,
+			NoticeMaxItems:     2,
+			NoticeMaxBacktrace: 1,
+		}
+		opt.init()
+	})
+
+	It("limits values and backtraces and records what was truncated", func() {
+		notice := NewNotice("hello", nil, 0)
+		notice.Params["body"] = strings.Repeat("é", 10)
+		notice.Params["nested"] = map[string]interface{}{
+			"a": map[string]interface{}{
+				"b": map[string]interface{}{"c": 1},
+			},
+		}
+		notice.Params["list"] = []interface{}{1, 2, 3}
+		notice.Params["typed"] = map[string]int{"a": 1, "b": 2, "c": 3}
+		notice.Session["user"] = "alice"
+
+		opt.truncateNotice(notice)
+
+		Expect(notice.Params["body"]).To(Equal("éééé…"))
+		Expect(notice.Params["nested"]).To(Equal(map[string]interface{}{
+			"a": map[string]interface{}{"b": truncatedValue},
+		}))
+		Expect(notice.Params["list"]).To(Equal([]interface{}{1, 2}))
+		Expect(notice.Params["typed"]).To(Equal(map[string]interface{}{
+			"a": 1.0, "b": 2.0,
+		}))
+		Expect(notice.Session["user"]).To(Equal("alice"))
+		Expect(notice.Errors[0].Backtrace).To(HaveLen(1))
+
+		truncated := notice.Context["truncated"].(map[string]interface{})
+		Expect(truncated["params"]).To(Equal(4))
+		Expect(truncated).NotTo(HaveKey("session"))
+		Expect(truncated).To(HaveKey("backtrace"))
+	})
+
+	It("does not limit values by default", func() {
+		opt := &NotifierOptions{
+			NoticeMaxDepth:     -1,
+			NoticeMaxStringLen: -1,
+			NoticeMaxItems:     -1,
+			NoticeMaxBacktrace: -1,
+		}
+		opt.init()
+		Expect(opt.NoticeMaxBacktrace).To(Equal(defaultNoticeMaxBacktrace))
+
+		for _, opt := range []*NotifierOptions{opt, {}} {
+			opt.init()
+			notice := NewNotice("hello", nil, 0)
+			notice.Params["body"] = strings.Repeat("x", 4096)
+			notice.Params["list"] = make([]interface{}, 256)
+			notice.Params["nested"] = map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": map[string]interface{}{
+						"c": map[string]interface{}{
+							"d": map[string]interface{}{
+								"e": map[string]interface{}{"f": 1},
+							},
+						},
+					},
+				},
+			}
+			opt.truncateNotice(notice)
+			Expect(notice.Params["body"]).To(HaveLen(4096))
+			Expect(notice.Params["list"]).To(HaveLen(256))
+			Expect(notice.Params["nested"]).To(HaveKey("a"))
+			Expect(notice.Context).NotTo(HaveKey("truncated"))
+			Expect(notice.Errors[0].Backtrace).NotTo(BeEmpty())
+		}
+	})
+})
+
+var _ = Describe("NoticeMaxSize", func() {
+	It("drops code hunks and params to fit the limit", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:     1,
+			ProjectKey:    "key",
+			Host:          "http://localhost:1",
+			NoticeMaxSize: 4096,
+		})
+		defer notifier.Close()
+
+		var sentNotice *Notice
+		notifier.AddFilter(func(notice *Notice) *Notice {
+			sentNotice = notice
+			return notice
+		})
+
+		notice := NewNotice("hello", nil, 0)
+		for _, k := range []string{"a", "b", "c", "d", "e", "f"} {
+			notice.Params[k] = strings.Repeat("x", 1000)
+		}
+		notifier.SendNotice(notice)
+
+		Expect(sentNotice).NotTo(BeNil())
+		Expect(sentNotice.Params["a"]).To(Equal(truncatedValue))
+		truncated := sentNotice.Context["truncated"].(map[string]interface{})
+		Expect(truncated["params"]).To(Equal(6))
+		Expect(truncated["size"]).To(BeNumerically(">", 4096))
+	})
+
+	It("limits values before dropping them", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:     1,
+			ProjectKey:    "key",
+			Host:          "http://localhost:1",
+			NoticeMaxSize: 8192,
+		})
+		defer notifier.Close()
+
+		var sentNotice *Notice
+		notifier.AddFilter(func(notice *Notice) *Notice {
+			sentNotice = notice
+			return notice
+		})
+
+		notice := NewNotice("hello", nil, 0)
+		notice.Params["body"] = strings.Repeat("x", 16384)
+		notice.Params["user"] = "alice"
+		notifier.SendNotice(notice)
+
+		Expect(sentNotice).NotTo(BeNil())
+		Expect(sentNotice.Params["body"]).To(HaveLen(shrinkMaxStringLen + len("…")))
+		Expect(sentNotice.Params["user"]).To(Equal("alice"))
+		truncated := sentNotice.Context["truncated"].(map[string]interface{})
+		Expect(truncated["params"]).To(Equal(1))
+	})
+
+	It("rejects notices that can not be shrunk", func() {
+		notifier := NewNotifierWithOptions(&NotifierOptions{
+			ProjectId:     1,
+			ProjectKey:    "key",
+			Host:          "http://localhost:1",
+			NoticeMaxSize: 1024,
+		})
+		defer notifier.Close()
+
+		_, err := notifier.SendNotice(NewNotice(strings.Repeat("x", 2048), nil, 0))
+		Expect(err).To(MatchError("gobrake: notice exceeds 1024 bytes max size limit"))
+	})
+})
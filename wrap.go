@@ -0,0 +1,74 @@
+package gobrake
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WrapOptions configures functions wrapped with WrapFunc and WrapFuncCtx.
+type WrapOptions struct {
+	// Name of the breakdown group the duration of the function is added
+	// to. Duration is not recorded when empty.
+	Group string
+	// Route metric the duration is added to. WrapFuncCtx defaults to the
+	// route metric carried by the context.
+	RouteMetric *RouteMetric
+	// ShouldNotify reports whether the returned error is sent to Airbrake.
+	// Default is to send all errors.
+	ShouldNotify func(error) bool
+}
+
+// WrapFunc returns function that calls fn recovering from panics. Panics
+// and errors returned by fn are sent to Airbrake. Recovered panic is
+// returned as an error, so the wrapped function can be passed to e.g.
+// errgroup.Group.Go.
+func (n *Notifier) WrapFunc(fn func() error, opt *WrapOptions) func() error {
+	return func() error {
+		return n.callWrapped(context.Background(), opt, nil, func(context.Context) error {
+			return fn()
+		})
+	}
+}
+
+// WrapFuncCtx is like WrapFunc, but fn receives the context. Notices are
+// sent with the context.
+func (n *Notifier) WrapFuncCtx(
+	fn func(context.Context) error, opt *WrapOptions,
+) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return n.callWrapped(ctx, opt, ContextRouteMetric(ctx), fn)
+	}
+}
+
+func (n *Notifier) callWrapped(
+	ctx context.Context, opt *WrapOptions, metric *RouteMetric, fn func(context.Context) error,
+) (err error) {
+	if opt == nil {
+		opt = new(WrapOptions)
+	}
+	if opt.RouteMetric != nil {
+		metric = opt.RouteMetric
+	}
+
+	start := time.Now()
+	defer func() {
+		if opt.Group != "" {
+			metric.AddGroup(opt.Group, time.Since(start))
+		}
+
+		if v := recover(); v != nil {
+			notice := n.Notice(v, nil, 2)
+			notice.Context["severity"] = "critical"
+			n.SendNoticeContext(ctx, notice)
+			err = fmt.Errorf("gobrake: recovered panic: %v", v)
+		}
+	}()
+
+	err = fn(ctx)
+	if err != nil && (opt.ShouldNotify == nil || opt.ShouldNotify(err)) {
+		notice := n.Notice(err, nil, 1)
+		n.SendNoticeAsyncContext(ctx, notice)
+	}
+	return err
+}
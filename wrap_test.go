@@ -0,0 +1,182 @@
+package gobrake_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/airbrake/gobrake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WrapFunc", func() {
+	var notifier *gobrake.Notifier
+	var mu sync.Mutex
+	var sentNotices []*gobrake.Notice
+
+	BeforeEach(func() {
+		sentNotices = nil
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			notice := new(gobrake.Notice)
+			err = json.Unmarshal(b, notice)
+			if err != nil {
+				panic(err)
+			}
+
+			mu.Lock()
+			sentNotices = append(sentNotices, notice)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("recovers panic and returns it as error", func() {
+		fn := notifier.WrapFunc(func() error {
+			panic("hello")
+		}, nil)
+
+		err := fn()
+		Expect(err).To(MatchError("gobrake: recovered panic: hello"))
+
+		notifier.Flush()
+		Expect(sentNotices).To(HaveLen(1))
+		Expect(sentNotices[0].Errors[0].Message).To(Equal("hello"))
+		Expect(sentNotices[0].Context["severity"]).To(Equal("critical"))
+	})
+
+	It("reports errors matching the predicate", func() {
+		errIgnored := errors.New("ignored")
+		fn := notifier.WrapFunc(func() error {
+			return errIgnored
+		}, &gobrake.WrapOptions{
+			ShouldNotify: func(err error) bool {
+				return err != errIgnored
+			},
+		})
+
+		Expect(fn()).To(Equal(errIgnored))
+		notifier.Flush()
+		Expect(sentNotices).To(BeEmpty())
+	})
+
+	It("adds duration to the breakdown group of the route metric", func() {
+		ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+		fn := notifier.WrapFuncCtx(func(ctx context.Context) error {
+			return nil
+		}, &gobrake.WrapOptions{
+			Group: "task",
+		})
+
+		Expect(fn(ctx)).NotTo(HaveOccurred())
+		Expect(metric.Groups()).To(HaveKey("task"))
+		Expect(notifier.NotifyRouteMetric(metric)).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("Group", func() {
+	var notifier *gobrake.Notifier
+	var sentNotice *gobrake.Notice
+	var requests int
+
+	BeforeEach(func() {
+		requests = 0
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				panic(err)
+			}
+
+			sentNotice = new(gobrake.Notice)
+			err = json.Unmarshal(b, sentNotice)
+			if err != nil {
+				panic(err)
+			}
+			requests++
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+
+		notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+			ProjectId:  1,
+			ProjectKey: "key",
+			Host:       server.URL,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(notifier.Close()).NotTo(HaveOccurred())
+	})
+
+	It("reports the first failing task with its name", func() {
+		ctx, metric := gobrake.NewRouteMetric(context.Background(), "GET", "/hello")
+		g, ctx := notifier.NewGroup(ctx)
+		g.SetLimit(1)
+
+		g.Go("ok", func(ctx context.Context) error {
+			return nil
+		})
+		g.Go("failing", func(ctx context.Context) error {
+			return errors.New("task failed")
+		})
+		g.Go("canceled", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		err := g.Wait()
+		Expect(err).To(MatchError("task failed"))
+
+		notifier.Flush()
+		Expect(requests).To(Equal(1))
+		Expect(sentNotice.Context["task"]).To(Equal("failing"))
+		Expect(metric.Groups()).To(HaveLen(1))
+		Expect(metric.Groups()).To(HaveKey("task"))
+	})
+
+	It("reports every panic with the backtrace of the panic", func() {
+		g, _ := notifier.NewGroup(context.Background())
+		g.SetLimit(1)
+
+		for _, name := range []string{"first", "second"} {
+			g.Go(name, func(ctx context.Context) error {
+				panic("boom")
+			})
+		}
+
+		err := g.Wait()
+		Expect(err).To(MatchError(`gobrake: task "first" panicked: boom`))
+
+		notifier.Flush()
+		Expect(requests).To(Equal(2))
+		Expect(sentNotice.Context["severity"]).To(Equal("critical"))
+		Expect(sentNotice.Errors[0].Message).To(Equal("boom"))
+		frame := sentNotice.Errors[0].Backtrace[0]
+		Expect(frame.File).To(HaveSuffix("wrap_test.go"))
+	})
+})